@@ -0,0 +1,408 @@
+// Package ai 五子棋引擎：候选点按棋型评分排序，识别成五和必须防守的冲四，
+// 在时间预算内用 alpha-beta 迭代加深搜索；另提供 VCF/VCT 威胁空间搜索（见 Solve）。
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"gomoku-backend/rules"
+)
+
+// Level 难度
+type Level string
+
+// 难度
+const (
+	Easy   Level = "easy"
+	Medium Level = "medium"
+	Hard   Level = "hard"
+)
+
+// ErrNoMove 棋盘已满，没有可落子的位置
+var ErrNoMove = errors.New("no legal move")
+
+// Config 引擎参数
+type Config struct {
+	MaxDepth    int           // 最大搜索深度（半回合）
+	Width       int           // 每个节点最多展开的候选点数
+	TimeBudget  time.Duration // 搜索时间预算，0 表示不限时，此时相同 Seed 的结果可复现
+	MaxNodes    int           // 搜索节点数上限，0 表示不限；与时间预算不同，按节点数截止不影响复现
+	Noise       int           // 根节点评分的随机扰动幅度，用于降低低难度的棋力
+	MultiPV     int           // 根节点精确计算评分的候选点数，默认 1（只保证最佳点的评分准确）
+	Seed        int64         // 随机种子，决定同分候选点的顺序和扰动
+	Threats     SolveMode     // 搜索前先尝试的威胁空间搜索（VCF 或 VCT），空表示不做
+	ThreatNodes int           // 威胁空间搜索的节点数上限
+}
+
+// levelConfigs 各难度的默认参数
+var levelConfigs = map[Level]Config{
+	Easy:   {MaxDepth: 2, Width: 6, TimeBudget: 300 * time.Millisecond, MaxNodes: 5000, Noise: 200},
+	Medium: {MaxDepth: 4, Width: 10, TimeBudget: time.Second, MaxNodes: 15000, Noise: 10, Threats: VCF, ThreatNodes: 2000},
+	Hard:   {MaxDepth: 10, Width: 12, TimeBudget: 3 * time.Second, MaxNodes: 50000, Threats: VCT, ThreatNodes: 10000},
+}
+
+// LevelConfig 返回难度对应的引擎参数
+func LevelConfig(level Level) (Config, error) {
+	cfg, ok := levelConfigs[level]
+	if !ok {
+		return Config{}, fmt.Errorf("unknown level %q", level)
+	}
+	return cfg, nil
+}
+
+// Move 落子点
+type Move struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// Candidate 候选点及其评分（行棋方角度）
+type Candidate struct {
+	Row   int `json:"row"`
+	Col   int `json:"col"`
+	Score int `json:"score"`
+}
+
+// Result 搜索结果
+type Result struct {
+	Best       Candidate   `json:"best"`
+	Candidates []Candidate `json:"candidates"` // 根节点候选点，按评分降序
+	PV         []Move      `json:"pv"`         // 主要变化，从最佳点开始双方交替
+	Depth      int         `json:"depth"`      // 完成的搜索深度
+	Nodes      int         `json:"nodes"`
+}
+
+// IsWin 评分表示行棋方必胜
+func (r *Result) IsWin() bool {
+	return IsWinScore(r.Best.Score)
+}
+
+// IsLoss 评分表示行棋方必败
+func (r *Result) IsLoss() bool {
+	return IsWinScore(-r.Best.Score)
+}
+
+// IsWinScore 判断评分是否表示已找到必胜
+func IsWinScore(score int) bool {
+	return score >= winThreshold
+}
+
+// Engine 搜索引擎，不是并发安全的
+type Engine struct {
+	rules rules.RuleSet
+	cfg   Config
+	rng   *rand.Rand
+}
+
+// NewEngine 创建引擎
+func NewEngine(ruleSet rules.RuleSet, cfg Config) *Engine {
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = 1
+	}
+	if cfg.Width <= 0 {
+		cfg.Width = 10
+	}
+	if cfg.MultiPV <= 0 {
+		cfg.MultiPV = 1
+	}
+	return &Engine{
+		rules: ruleSet,
+		cfg:   cfg,
+		rng:   rand.New(rand.NewSource(cfg.Seed)),
+	}
+}
+
+// scoredMove 带排序分的候选点
+type scoredMove struct {
+	Move
+	score int
+}
+
+// rootMove 根节点候选点的搜索结果
+type rootMove struct {
+	Move
+	score int
+	pv    []Move
+}
+
+// searcher 单次搜索的状态
+type searcher struct {
+	rules    rules.RuleSet
+	width    int
+	deadline time.Time
+	maxNodes int
+	nodes    int
+	aborted  bool
+}
+
+// Search 为 color 一方搜索最佳落子，board 不会被修改
+func (e *Engine) Search(board [][]int, color int) (*Result, error) {
+	b := copyBoard(board)
+	s := &searcher{rules: e.rules, width: e.cfg.Width, maxNodes: e.cfg.MaxNodes}
+	if e.cfg.TimeBudget > 0 {
+		s.deadline = time.Now().Add(e.cfg.TimeBudget)
+	}
+
+	moves, win := s.generate(b, color, e.rng)
+	if win != nil {
+		best := Candidate{Row: win.Row, Col: win.Col, Score: WinScore}
+		return &Result{Best: best, Candidates: []Candidate{best}, PV: []Move{*win}, Depth: 1, Nodes: 1}, nil
+	}
+	if len(moves) == 0 {
+		return nil, ErrNoMove
+	}
+	if result := e.solveThreats(b, color); result != nil {
+		return result, nil
+	}
+
+	roots := make([]rootMove, len(moves))
+	for i, m := range moves {
+		roots[i] = rootMove{Move: m.Move}
+	}
+
+	completed := 0
+	for depth := 1; depth <= e.cfg.MaxDepth; depth++ {
+		scored, ok := s.searchRoot(b, color, roots, depth, e.cfg.MultiPV)
+		if !ok {
+			break
+		}
+		roots = scored
+		completed = depth
+
+		// 已找到必胜或必败，更深的搜索不会改变结论
+		if abs(roots[0].score) >= winThreshold {
+			break
+		}
+	}
+
+	result := &Result{Depth: completed, Nodes: s.nodes}
+	for _, r := range roots {
+		result.Candidates = append(result.Candidates, Candidate{Row: r.Row, Col: r.Col, Score: r.score})
+	}
+
+	best := roots[0]
+	if e.cfg.Noise > 0 && completed > 0 && abs(best.score) < winThreshold {
+		best = e.pickWithNoise(roots)
+	}
+	result.Best = Candidate{Row: best.Row, Col: best.Col, Score: best.score}
+	result.PV = append([]Move{best.Move}, best.pv...)
+	return result, nil
+}
+
+// solveThreats 用威胁空间搜索寻找必胜序列，找到时直接作为搜索结果，最多占用一半的时间预算
+func (e *Engine) solveThreats(board [][]int, color int) *Result {
+	if e.cfg.Threats == "" {
+		return nil
+	}
+	cfg := DefaultSolveConfig(e.cfg.Threats)
+	cfg.MaxNodes = e.cfg.ThreatNodes
+	cfg.TimeBudget = e.cfg.TimeBudget / 2
+
+	sol, err := Solve(e.rules, board, color, e.cfg.Threats, cfg)
+	if err != nil || !sol.Found {
+		return nil
+	}
+	best := Candidate{Row: sol.Line[0].Row, Col: sol.Line[0].Col, Score: WinScore - (len(sol.Line) - 1)}
+	pv := sol.Line
+	if sol.DefenderForbidden {
+		// 主要变化要求双方交替，止于防守方无法阻挡的冲四
+		pv = pv[:len(pv)-1]
+	}
+	return &Result{Best: best, Candidates: []Candidate{best}, PV: pv, Depth: len(sol.Line), Nodes: sol.Nodes}
+}
+
+// pickWithNoise 给非必败的候选点评分加上随机扰动后选择，低难度借此下出次优手
+func (e *Engine) pickWithNoise(roots []rootMove) rootMove {
+	best, bestScore := roots[0], roots[0].score+e.rng.Intn(e.cfg.Noise+1)
+	for _, r := range roots[1:] {
+		if r.score <= -winThreshold {
+			continue
+		}
+		if score := r.score + e.rng.Intn(e.cfg.Noise+1); score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	return best
+}
+
+// searchRoot 以给定深度搜索根节点的所有候选点，返回按评分降序排列的结果，超时返回 false
+//
+// 前 multiPV 个候选点以完整窗口搜索得到准确评分，其余候选点只需证明不优于第 multiPV 名。
+func (s *searcher) searchRoot(b [][]int, color int, roots []rootMove, depth, multiPV int) ([]rootMove, bool) {
+	scored := make([]rootMove, 0, len(roots))
+	for _, r := range roots {
+		alpha := -2 * WinScore
+		if len(scored) >= multiPV {
+			alpha = kthBest(scored, multiPV)
+		}
+
+		b[r.Row][r.Col] = color
+		score, pv := s.negamax(b, opponent(color), depth-1, 1, -2*WinScore, -alpha)
+		b[r.Row][r.Col] = rules.Empty
+		if s.aborted {
+			return nil, false
+		}
+
+		scored = append(scored, rootMove{Move: r.Move, score: -score, pv: pv})
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	return scored, true
+}
+
+// negamax alpha-beta 搜索，返回 color 一方的评分和主要变化
+func (s *searcher) negamax(b [][]int, color, depth, ply, alpha, beta int) (int, []Move) {
+	s.nodes++
+	if s.maxNodes > 0 && s.nodes > s.maxNodes {
+		s.aborted = true
+	}
+	if s.nodes&255 == 0 && !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.aborted = true
+	}
+	if s.aborted {
+		return 0, nil
+	}
+
+	if depth == 0 {
+		if win := s.findWin(b, color); win != nil {
+			return WinScore - ply, []Move{*win}
+		}
+		return evaluate(b, color), nil
+	}
+
+	moves, win := s.generate(b, color, nil)
+	if win != nil {
+		return WinScore - ply, []Move{*win}
+	}
+	if len(moves) == 0 {
+		return 0, nil // 棋盘已满或只剩禁手点，按和棋处理
+	}
+
+	best, bestPV := -2*WinScore, []Move(nil)
+	for _, m := range moves {
+		b[m.Row][m.Col] = color
+		score, pv := s.negamax(b, opponent(color), depth-1, ply+1, -beta, -alpha)
+		b[m.Row][m.Col] = rules.Empty
+		if s.aborted {
+			return 0, nil
+		}
+
+		score = -score
+		if score > best {
+			best = score
+			bestPV = append([]Move{m.Move}, pv...)
+		}
+		if best > alpha {
+			alpha = best
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best, bestPV
+}
+
+// generate 生成 color 一方的候选点
+//
+// 能直接获胜时返回获胜点；对方有成五点时只返回能堵住的点；否则按进攻和防守价值排序，
+// 跳过禁手点后最多返回 width 个。rng 不为 nil 时先打乱顺序，使同分候选点的先后由随机种子决定。
+func (s *searcher) generate(b [][]int, color int, rng *rand.Rand) ([]scoredMove, *Move) {
+	opp := opponent(color)
+	n := len(b)
+
+	var candidates, blocks []scoredMove
+	empty := true
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			if b[r][c] != rules.Empty {
+				empty = false
+				continue
+			}
+			if !hasNeighbor(b, r, c) {
+				continue
+			}
+
+			attack, five := pointScore(b, r, c, color)
+			if five && s.wins(b, r, c, color) {
+				return nil, &Move{Row: r, Col: c}
+			}
+			defense, oppFive := pointScore(b, r, c, opp)
+
+			m := scoredMove{Move: Move{Row: r, Col: c}, score: attack + defense*4/5}
+			if oppFive && s.wins(b, r, c, opp) {
+				blocks = append(blocks, m)
+			}
+			candidates = append(candidates, m)
+		}
+	}
+
+	if empty {
+		return []scoredMove{{Move: Move{Row: n / 2, Col: n / 2}}}, nil
+	}
+	if len(blocks) > 0 {
+		candidates = blocks
+	}
+
+	if rng != nil {
+		rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	moves := candidates[:0]
+	for _, m := range candidates {
+		if len(moves) >= s.width {
+			break
+		}
+		if s.rules.CheckForbidden(b, m.Row, m.Col, color) != rules.NotForbidden {
+			continue
+		}
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+// findWin 查找 color 一方能直接获胜的点
+func (s *searcher) findWin(b [][]int, color int) *Move {
+	n := len(b)
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			if b[r][c] != rules.Empty || !hasNeighbor(b, r, c) {
+				continue
+			}
+			if _, five := pointScore(b, r, c, color); five && s.wins(b, r, c, color) {
+				return &Move{Row: r, Col: c}
+			}
+		}
+	}
+	return nil
+}
+
+// wins 判断 color 一方在 (row, col) 落子是否获胜
+func (s *searcher) wins(b [][]int, row, col, color int) bool {
+	b[row][col] = color
+	win := s.rules.IsWin(b, row, col)
+	b[row][col] = rules.Empty
+	return win
+}
+
+// kthBest 返回已排序或未排序结果中第 k 高的评分
+func kthBest(scored []rootMove, k int) int {
+	scores := make([]int, len(scored))
+	for i, r := range scored {
+		scores[i] = r.score
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(scores)))
+	return scores[k-1]
+}
+
+// abs 返回绝对值
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
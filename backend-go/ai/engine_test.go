@@ -0,0 +1,244 @@
+package ai
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gomoku-backend/rules"
+)
+
+// parseBoard 把棋盘图转换为 15 路棋盘，'X' 为黑子，'O' 为白子，'.' 为空点；未写出的行和列都是空点
+func parseBoard(t testing.TB, diagram string) [][]int {
+	t.Helper()
+	const size = 15
+	board := make([][]int, size)
+	for i := range board {
+		board[i] = make([]int, size)
+	}
+
+	lines := strings.Split(strings.Trim(diagram, "\n"), "\n")
+	for r, line := range lines {
+		for c, ch := range strings.TrimSpace(line) {
+			switch ch {
+			case 'X':
+				board[r][c] = rules.Black
+			case 'O':
+				board[r][c] = rules.White
+			case '.':
+			default:
+				t.Fatalf("unexpected %q in diagram", ch)
+			}
+		}
+	}
+	return board
+}
+
+// levelEngine 按难度创建只按节点数截止的引擎，搜索结果只取决于局面和种子
+func levelEngine(t testing.TB, level Level, ruleName string, seed int64) *Engine {
+	t.Helper()
+	cfg, err := LevelConfig(level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.TimeBudget = 0
+	cfg.Seed = seed
+	ruleSet, err := rules.Get(ruleName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewEngine(ruleSet, cfg)
+}
+
+var levels = []Level{Easy, Medium, Hard}
+
+const middleGame = `
+...............
+...............
+...............
+...............
+.....O.........
+......XO.......
+.....XXO.......
+....O.X........
+.......X.......
+...............`
+
+func TestSearchSameSeed(t *testing.T) {
+	for _, level := range levels {
+		t.Run(string(level), func(t *testing.T) {
+			board := parseBoard(t, middleGame)
+			first, err := levelEngine(t, level, rules.Freestyle, 42).Search(board, rules.White)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := levelEngine(t, level, rules.Freestyle, 42).Search(board, rules.White)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first.Best != second.Best || !reflect.DeepEqual(first.PV, second.PV) {
+				t.Errorf("same seed gave %+v %v and %+v %v", first.Best, first.PV, second.Best, second.PV)
+			}
+		})
+	}
+}
+
+func TestSearchTakesFive(t *testing.T) {
+	// 双方都有冲四，轮到黑方时应直接成五而不是去堵
+	board := parseBoard(t, `
+...............
+...............
+...............
+..XOOOO........
+...............
+...............
+...............
+...OXXXX.......`)
+
+	for _, level := range levels {
+		t.Run(string(level), func(t *testing.T) {
+			for _, ruleName := range []string{rules.Freestyle, rules.Renju} {
+				result, err := levelEngine(t, level, ruleName, 1).Search(board, rules.Black)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := (Move{Row: result.Best.Row, Col: result.Best.Col}); got != (Move{Row: 7, Col: 8}) {
+					t.Errorf("%s: best = %v, want the five at (7, 8)", ruleName, got)
+				}
+				if !result.IsWin() {
+					t.Errorf("%s: score %d is not a win", ruleName, result.Best.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchBlocksFour(t *testing.T) {
+	tests := []struct {
+		name    string
+		diagram string
+		want    []Move // 任一即可
+	}{
+		{
+			name: "four",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+....OO.........
+...OXXXX.......
+.....X.........`,
+			want: []Move{{Row: 7, Col: 8}},
+		},
+		{
+			name: "broken four",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+....OO.........
+...OXX.XX......
+.....X.........`,
+			want: []Move{{Row: 7, Col: 6}},
+		},
+		{
+			name: "open four",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+....OO.O.......
+...XXXX........
+.....O.........`,
+			want: []Move{{Row: 7, Col: 2}, {Row: 7, Col: 7}},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, level := range levels {
+			t.Run(tt.name+"/"+string(level), func(t *testing.T) {
+				board := parseBoard(t, tt.diagram)
+				result, err := levelEngine(t, level, rules.Freestyle, 1).Search(board, rules.White)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := Move{Row: result.Best.Row, Col: result.Best.Col}
+				for _, m := range tt.want {
+					if got == m {
+						return
+					}
+				}
+				t.Errorf("best = %v, want one of %v", got, tt.want)
+			})
+		}
+	}
+}
+
+func TestSearchPicksEmptySquare(t *testing.T) {
+	positions := map[string]string{
+		"empty board": ``,
+		"middle game": middleGame,
+		"corner": `
+XOX............
+OXO............
+XO.............
+O..............`,
+		"edge": `
+...............
+...............
+...............
+...............
+..............X
+.............OX
+..............O
+.............XO
+..............X`,
+	}
+
+	for name, diagram := range positions {
+		for _, level := range []Level{Easy, Medium} {
+			t.Run(name+"/"+string(level), func(t *testing.T) {
+				for seed := int64(1); seed <= 20; seed++ {
+					for _, color := range []int{rules.Black, rules.White} {
+						board := parseBoard(t, diagram)
+						before := copyBoard(board)
+
+						result, err := levelEngine(t, level, rules.Renju, seed).Search(board, color)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if !reflect.DeepEqual(board, before) {
+							t.Fatal("Search modified the board")
+						}
+						r, c := result.Best.Row, result.Best.Col
+						if !inBoard(board, r, c) {
+							t.Fatalf("seed %d color %d: best (%d, %d) is off the board", seed, color, r, c)
+						}
+						if board[r][c] != rules.Empty {
+							t.Fatalf("seed %d color %d: best (%d, %d) is occupied", seed, color, r, c)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSearchFullBoard(t *testing.T) {
+	board := make([][]int, 3)
+	for r := range board {
+		board[r] = []int{rules.Black, rules.White, rules.Black}
+	}
+	if _, err := levelEngine(t, Easy, rules.Freestyle, 1).Search(board, rules.Black); err != ErrNoMove {
+		t.Errorf("Search on a full board: err = %v, want ErrNoMove", err)
+	}
+}
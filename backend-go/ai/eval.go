@@ -0,0 +1,109 @@
+package ai
+
+import "gomoku-backend/rules"
+
+// 评分常量
+const (
+	// WinScore 必胜局面的评分，实际返回 WinScore - 步数，越快获胜评分越高
+	WinScore = 100000000
+	// winThreshold 评分绝对值超过该值表示已找到必胜或必败
+	winThreshold = WinScore - 1000
+)
+
+// windowWeights 五格窗口内只有一方棋子时按子数计分
+var windowWeights = [6]int{0, 1, 10, 100, 1000, WinScore}
+
+// directions 四个方向：水平、垂直、主对角线、副对角线
+var directions = [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+
+// opponent 返回对方颜色
+func opponent(color int) int {
+	return 3 - color
+}
+
+// inBoard 判断坐标是否在棋盘内
+func inBoard(board [][]int, row, col int) bool {
+	return row >= 0 && row < len(board) && col >= 0 && col < len(board[row])
+}
+
+// evaluate 静态评估局面，从 color 一方的角度计分
+//
+// 扫描棋盘上所有五格窗口，只含一方棋子的窗口按子数计分，活型会被多个窗口重复计入，
+// 因此自然比冲型得分更高。
+func evaluate(board [][]int, color int) int {
+	var scores [3]int
+	n := len(board)
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			for _, d := range directions {
+				endR, endC := r+d[0]*4, c+d[1]*4
+				if !inBoard(board, endR, endC) {
+					continue
+				}
+
+				var counts [3]int
+				for i := 0; i < 5; i++ {
+					counts[board[r+d[0]*i][c+d[1]*i]]++
+				}
+				switch {
+				case counts[rules.White] == 0 && counts[rules.Black] > 0:
+					scores[rules.Black] += windowWeights[counts[rules.Black]]
+				case counts[rules.Black] == 0 && counts[rules.White] > 0:
+					scores[rules.White] += windowWeights[counts[rules.White]]
+				}
+			}
+		}
+	}
+	return scores[color] - scores[opponent(color)]
+}
+
+// pointScore 评估 color 一方在空点 (row, col) 落子的价值，five 表示该点可能成五
+//
+// 只统计经过该点且不含对方棋子的窗口，用于候选点排序和威胁识别，成五仍需用规则确认。
+func pointScore(board [][]int, row, col, color int) (score int, five bool) {
+	opp := opponent(color)
+	for _, d := range directions {
+		for start := -4; start <= 0; start++ {
+			own, blocked := 0, false
+			for i := start; i < start+5; i++ {
+				r, c := row+d[0]*i, col+d[1]*i
+				if !inBoard(board, r, c) || board[r][c] == opp {
+					blocked = true
+					break
+				}
+				if board[r][c] == color {
+					own++
+				}
+			}
+			if blocked {
+				continue
+			}
+			score += windowWeights[own+1]
+			if own == 4 {
+				five = true
+			}
+		}
+	}
+	return score, five
+}
+
+// hasNeighbor 判断 (row, col) 周围两格内是否有棋子
+func hasNeighbor(board [][]int, row, col int) bool {
+	for r := row - 2; r <= row+2; r++ {
+		for c := col - 2; c <= col+2; c++ {
+			if inBoard(board, r, c) && board[r][c] != rules.Empty {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyBoard 复制棋盘，搜索过程中会临时修改棋盘
+func copyBoard(board [][]int) [][]int {
+	b := make([][]int, len(board))
+	for i := range board {
+		b[i] = append([]int(nil), board[i]...)
+	}
+	return b
+}
@@ -0,0 +1,493 @@
+package ai
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"gomoku-backend/rules"
+)
+
+// SolveMode 威胁空间搜索的类型
+type SolveMode string
+
+// 威胁空间搜索
+const (
+	VCF SolveMode = "vcf" // Victory by Continuous Fours：每一步都冲四
+	VCT SolveMode = "vct" // Victory by Continuous Threats：每一步都冲四或活三
+)
+
+// SolveConfig 威胁空间搜索的限制
+type SolveConfig struct {
+	MaxDepth   int           // 进攻方最多落子数
+	MaxNodes   int           // 搜索节点数上限，0 表示不限
+	TimeBudget time.Duration // 搜索时间预算，0 表示不限时
+}
+
+// DefaultSolveConfig 返回各类搜索的默认限制
+func DefaultSolveConfig(mode SolveMode) SolveConfig {
+	if mode == VCT {
+		return SolveConfig{MaxDepth: 8, MaxNodes: 20000}
+	}
+	return SolveConfig{MaxDepth: 20, MaxNodes: 20000}
+}
+
+// Solution 威胁空间搜索结果
+type Solution struct {
+	Mode    SolveMode `json:"mode"`
+	Found   bool      `json:"found"`
+	Line    []Move    `json:"line,omitempty"`    // 必胜序列，进攻方先行、双方交替，以进攻方成五结束
+	Nodes   int       `json:"nodes"`             // 搜索的节点数
+	Aborted bool      `json:"aborted,omitempty"` // 达到节点数或时间上限而停止，未找到不代表不存在
+
+	// DefenderForbidden 进攻方最后一手冲四的成五点是防守方的禁手，防守方无法阻挡，
+	// 此时序列最后两手都是进攻方的：冲四之后直接在该点成五
+	DefenderForbidden bool `json:"defenderForbidden,omitempty"`
+}
+
+// noReply 必胜序列中防守方无合法应手的占位，Solve 返回前去掉
+var noReply = Move{Row: -1, Col: -1}
+
+// zobrist 局面哈希用的随机数，按 [行*MaxBoardSide+列][颜色] 索引
+var zobrist = func() [maxBoardSide * maxBoardSide][3]uint64 {
+	var table [maxBoardSide * maxBoardSide][3]uint64
+	rng := rand.New(rand.NewSource(1))
+	for i := range table {
+		table[i][rules.Black] = rng.Uint64()
+		table[i][rules.White] = rng.Uint64()
+	}
+	return table
+}()
+
+// maxBoardSide 支持的最大棋盘边长
+const maxBoardSide = 19
+
+// solver 威胁空间搜索的状态
+//
+// 进攻方只走冲四（VCF）或冲四、活三（VCT），防守方对冲四只能挡在成五点，
+// 对活三则尝试所有能阻止对方成活四的点以及自己的冲四反击。对方有冲四时进攻方必须先挡住，
+// 且挡的这一手本身也要是威胁。连珠规则下进攻方不走禁手，防守方只能在禁手点挡冲四时判负。
+type solver struct {
+	rules    rules.RuleSet
+	b        [][]int
+	attacker int
+	defender int
+	vct      bool
+	maxNodes int
+	deadline time.Time
+	nodes    int
+	aborted  bool
+	hash     uint64
+	near     [3][maxBoardSide * maxBoardSide]int // 各点四条线上距离 4 以内某方的棋子数，用于快速排除
+	failed   map[uint64]int                      // 已证明在给定深度内无法取胜的局面 -> 深度
+}
+
+// Solve 搜索 color 一方的 VCF 或 VCT 必胜序列，board 不会被修改
+func Solve(ruleSet rules.RuleSet, board [][]int, color int, mode SolveMode, cfg SolveConfig) (*Solution, error) {
+	if mode != VCF && mode != VCT {
+		return nil, fmt.Errorf("unknown solve mode %q", mode)
+	}
+	if len(board) > maxBoardSide {
+		return nil, fmt.Errorf("board larger than %dx%d is not supported", maxBoardSide, maxBoardSide)
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = DefaultSolveConfig(mode).MaxDepth
+	}
+
+	s := &solver{
+		rules:    ruleSet,
+		b:        copyBoard(board),
+		attacker: color,
+		defender: opponent(color),
+		vct:      mode == VCT,
+		maxNodes: cfg.MaxNodes,
+		failed:   make(map[uint64]int),
+	}
+	if cfg.TimeBudget > 0 {
+		s.deadline = time.Now().Add(cfg.TimeBudget)
+	}
+	for r := range s.b {
+		for c, cell := range s.b[r] {
+			if cell != rules.Empty {
+				s.place(Move{Row: r, Col: c}, cell)
+			}
+		}
+	}
+
+	// 迭代加深，优先找到最短的必胜序列
+	for depth := 1; depth <= cfg.MaxDepth && !s.aborted; depth++ {
+		if line, ok := s.attack(depth); ok {
+			sol := &Solution{Mode: mode, Found: true, Line: line, Nodes: s.nodes}
+			if n := len(line); n >= 3 && line[n-2] == noReply {
+				sol.Line = append(line[:n-2], line[n-1])
+				sol.DefenderForbidden = true
+			}
+			return sol, nil
+		}
+	}
+	return &Solution{Mode: mode, Nodes: s.nodes, Aborted: s.aborted}, nil
+}
+
+// attack 进攻方落子的节点，返回从此开始的必胜序列
+func (s *solver) attack(depth int) ([]Move, bool) {
+	s.nodes++
+	if s.maxNodes > 0 && s.nodes > s.maxNodes {
+		s.aborted = true
+	}
+	if !s.deadline.IsZero() && s.nodes%64 == 0 && time.Now().After(s.deadline) {
+		s.aborted = true
+	}
+	if s.aborted {
+		return nil, false
+	}
+
+	if win := s.firstFivePoint(s.attacker); win != nil {
+		return []Move{*win}, true
+	}
+	if depth == 0 {
+		return nil, false
+	}
+	if d, ok := s.failed[s.hash]; ok && d >= depth {
+		return nil, false
+	}
+
+	// 对方有冲四时必须先挡住
+	var mustBlock *Move
+	switch blocks := s.fivePoints(s.defender); {
+	case len(blocks) > 1:
+		s.markFailed(depth)
+		return nil, false
+	case len(blocks) == 1:
+		mustBlock = &blocks[0]
+	}
+
+	fours, threes := s.threatMoves(mustBlock)
+	for _, m := range fours {
+		if line, ok := s.tryFour(m, depth); ok {
+			return line, true
+		}
+		if s.aborted {
+			return nil, false
+		}
+	}
+	for _, m := range threes {
+		if line, ok := s.tryThree(m, depth); ok {
+			return line, true
+		}
+		if s.aborted {
+			return nil, false
+		}
+	}
+
+	s.markFailed(depth)
+	return nil, false
+}
+
+// tryFour 进攻方冲四，防守方只能挡在成五点
+func (s *solver) tryFour(m Move, depth int) ([]Move, bool) {
+	s.place(m, s.attacker)
+	defer s.remove(m, s.attacker)
+
+	fives := s.fivePointsNear(m, s.attacker)
+	switch {
+	case len(fives) == 0:
+		return nil, false
+	case len(fives) > 1:
+		// 活四或双四，挡不住
+		return []Move{m, fives[0], fives[1]}, true
+	}
+
+	block := fives[0]
+	if s.rules.CheckForbidden(s.b, block.Row, block.Col, s.defender) != rules.NotForbidden {
+		// 防守方只能在禁手点防守，无法阻挡，进攻方下一手在该点成五
+		return []Move{m, noReply, block}, true
+	}
+
+	s.place(block, s.defender)
+	line, ok := s.attack(depth - 1)
+	s.remove(block, s.defender)
+	if !ok {
+		return nil, false
+	}
+	return append([]Move{m, block}, line...), true
+}
+
+// tryThree 进攻方活三，所有防守都必须被攻破；返回防守方抵抗最久的变化
+func (s *solver) tryThree(m Move, depth int) ([]Move, bool) {
+	s.place(m, s.attacker)
+	defer s.remove(m, s.attacker)
+
+	// 所有防守点都是禁手时防守方同样挡不住，但之后的着法取决于防守方落在哪里，
+	// 无法给出完整的序列，不算作取胜
+	defenses := s.defenses()
+	if len(defenses) == 0 {
+		return nil, false
+	}
+
+	var longest []Move
+	for _, d := range defenses {
+		s.place(d, s.defender)
+		line, ok := s.attack(depth - 1)
+		s.remove(d, s.defender)
+		if !ok {
+			return nil, false
+		}
+		if len(line)+1 > len(longest) {
+			longest = append([]Move{d}, line...)
+		}
+	}
+	return append([]Move{m}, longest...), true
+}
+
+// threatMoves 生成进攻方的冲四点和（VCT 时）活三点，mustBlock 不为 nil 时只能走该点
+func (s *solver) threatMoves(mustBlock *Move) (fours, threes []Move) {
+	consider := func(r, c int) {
+		if s.b[r][c] != rules.Empty || !s.hasWindow(r, c, s.attacker, 2) {
+			return
+		}
+		if s.rules.CheckForbidden(s.b, r, c, s.attacker) != rules.NotForbidden {
+			return
+		}
+
+		m := Move{Row: r, Col: c}
+		if s.hasWindow(r, c, s.attacker, 3) {
+			s.place(m, s.attacker)
+			four := len(s.fivePointsNear(m, s.attacker)) > 0
+			s.remove(m, s.attacker)
+			if four {
+				fours = append(fours, m)
+				return
+			}
+		}
+		if s.vct && s.makesOpenFourThreat(m) {
+			threes = append(threes, m)
+		}
+	}
+
+	if mustBlock != nil {
+		consider(mustBlock.Row, mustBlock.Col)
+		return fours, threes
+	}
+	for r := range s.b {
+		for c := range s.b[r] {
+			consider(r, c)
+		}
+	}
+	return fours, threes
+}
+
+// makesOpenFourThreat 判断进攻方在 m 落子后是否能在同一条线上再下一手成活四（或双四）
+func (s *solver) makesOpenFourThreat(m Move) bool {
+	s.place(m, s.attacker)
+	defer s.remove(m, s.attacker)
+
+	for _, d := range directions {
+		if !s.lineWindow(m.Row, m.Col, d, s.attacker, 3) {
+			continue
+		}
+		for i := -4; i <= 4; i++ {
+			r, c := m.Row+d[0]*i, m.Col+d[1]*i
+			if i == 0 || !inBoard(s.b, r, c) || s.b[r][c] != rules.Empty {
+				continue
+			}
+			if s.lineWindow(r, c, d, s.attacker, 3) && s.winsNext(Move{Row: r, Col: c}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// winsNext 判断进攻方在空点 m 落子后是否有两个以上的成五点（挡不住）
+func (s *solver) winsNext(m Move) bool {
+	if !s.hasWindow(m.Row, m.Col, s.attacker, 3) {
+		return false
+	}
+	if s.rules.CheckForbidden(s.b, m.Row, m.Col, s.attacker) != rules.NotForbidden {
+		return false
+	}
+	s.place(m, s.attacker)
+	fives := s.fivePointsNear(m, s.attacker)
+	s.remove(m, s.attacker)
+	return len(fives) > 1
+}
+
+// defenses 生成防守方应对活三的候选点：进攻方所有成活四的点及其成五点，加上防守方的冲四反击
+func (s *solver) defenses() []Move {
+	seen := make(map[Move]bool)
+	var moves []Move
+	add := func(m Move) {
+		if seen[m] {
+			return
+		}
+		seen[m] = true
+		if s.rules.CheckForbidden(s.b, m.Row, m.Col, s.defender) == rules.NotForbidden {
+			moves = append(moves, m)
+		}
+	}
+
+	for r := range s.b {
+		for c := range s.b[r] {
+			if s.b[r][c] != rules.Empty {
+				continue
+			}
+			m := Move{Row: r, Col: c}
+			if s.winsNext(m) {
+				add(m)
+				s.place(m, s.attacker)
+				for _, f := range s.fivePointsNear(m, s.attacker) {
+					add(f)
+				}
+				s.remove(m, s.attacker)
+			}
+		}
+	}
+
+	// 冲四反击
+	for r := range s.b {
+		for c := range s.b[r] {
+			if s.b[r][c] != rules.Empty || !s.hasWindow(r, c, s.defender, 3) {
+				continue
+			}
+			m := Move{Row: r, Col: c}
+			s.place(m, s.defender)
+			four := len(s.fivePointsNear(m, s.defender)) > 0
+			s.remove(m, s.defender)
+			if four {
+				add(m)
+			}
+		}
+	}
+	return moves
+}
+
+// fivePoints 返回 color 一方所有的成五点
+func (s *solver) fivePoints(color int) []Move {
+	var points []Move
+	for r := range s.b {
+		for c := range s.b[r] {
+			if s.b[r][c] == rules.Empty && s.hasWindow(r, c, color, 4) && s.wins(r, c, color) {
+				points = append(points, Move{Row: r, Col: c})
+			}
+		}
+	}
+	return points
+}
+
+// firstFivePoint 返回 color 一方的任一成五点
+func (s *solver) firstFivePoint(color int) *Move {
+	for r := range s.b {
+		for c := range s.b[r] {
+			if s.b[r][c] == rules.Empty && s.hasWindow(r, c, color, 4) && s.wins(r, c, color) {
+				return &Move{Row: r, Col: c}
+			}
+		}
+	}
+	return nil
+}
+
+// fivePointsNear 返回经过 m 的四条线上 color 一方的成五点，m 处应已有 color 的棋子
+func (s *solver) fivePointsNear(m Move, color int) []Move {
+	var points []Move
+	for _, d := range directions {
+		for i := -4; i <= 4; i++ {
+			r, c := m.Row+d[0]*i, m.Col+d[1]*i
+			if i == 0 || !inBoard(s.b, r, c) || s.b[r][c] != rules.Empty {
+				continue
+			}
+			if s.hasWindow(r, c, color, 4) && s.wins(r, c, color) {
+				points = append(points, Move{Row: r, Col: c})
+			}
+		}
+	}
+	return points
+}
+
+// hasWindow 判断经过空点 (row, col) 的五格窗口中是否有不含对方棋子且至少有 need 个 color 棋子的
+func (s *solver) hasWindow(row, col, color, need int) bool {
+	if s.near[color][row*maxBoardSide+col] < need {
+		return false
+	}
+	for _, d := range directions {
+		if s.lineWindow(row, col, d, color, need) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineWindow 与 hasWindow 相同，但只检查方向 d 上的窗口
+func (s *solver) lineWindow(row, col int, d [2]int, color, need int) bool {
+	// 沿方向取 9 格，遇到棋盘边界或对方棋子时重新计数，连续可用格数达到 5 时检查窗口内的棋子数
+	size, opp := len(s.b), opponent(color)
+	var own [9]int
+	run := 0
+	for i := 0; i < 9; i++ {
+		r, c := row+d[0]*(i-4), col+d[1]*(i-4)
+		if r < 0 || r >= size || c < 0 || c >= size || s.b[r][c] == opp {
+			if i >= 4 {
+				return false
+			}
+			run = 0
+			continue
+		}
+		run++
+		if s.b[r][c] == color {
+			own[i] = 1
+		}
+		if i > 0 {
+			own[i] += own[i-1]
+		}
+		if run >= 5 {
+			count := own[i]
+			if i >= 5 {
+				count -= own[i-5]
+			}
+			if count >= need {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wins 判断 color 一方在空点 (row, col) 落子是否成五
+func (s *solver) wins(row, col, color int) bool {
+	s.b[row][col] = color
+	win := s.rules.IsWin(s.b, row, col)
+	s.b[row][col] = rules.Empty
+	return win
+}
+
+// place 落子并更新局面哈希
+func (s *solver) place(m Move, color int) {
+	s.b[m.Row][m.Col] = color
+	s.hash ^= zobrist[m.Row*maxBoardSide+m.Col][color]
+	s.addNear(m, color, 1)
+}
+
+// remove 撤回落子并更新局面哈希
+func (s *solver) remove(m Move, color int) {
+	s.b[m.Row][m.Col] = rules.Empty
+	s.hash ^= zobrist[m.Row*maxBoardSide+m.Col][color]
+	s.addNear(m, color, -1)
+}
+
+// addNear 更新 m 四条线上距离 4 以内各点的 near 计数
+func (s *solver) addNear(m Move, color, delta int) {
+	for _, d := range directions {
+		for i := -4; i <= 4; i++ {
+			r, c := m.Row+d[0]*i, m.Col+d[1]*i
+			if i != 0 && inBoard(s.b, r, c) {
+				s.near[color][r*maxBoardSide+c] += delta
+			}
+		}
+	}
+}
+
+// markFailed 记录当前局面在 depth 内无法取胜，搜索被中止时结论不可靠，不记录
+func (s *solver) markFailed(depth int) {
+	if !s.aborted {
+		s.failed[s.hash] = depth
+	}
+}
@@ -0,0 +1,228 @@
+package ai
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gomoku-backend/rules"
+)
+
+// solvePosition 已知结论的局面，棋子坐标为 "行,列"，从 0 开始
+type solvePosition struct {
+	name      string
+	rules     string
+	black     string
+	white     string
+	color     int  // 进攻方
+	vcf, vct  bool // 预期是否存在必胜序列
+	forbidden bool // 预期防守方只能在禁手点挡冲四
+}
+
+// solveCorpus 已知结论的局面集
+var solveCorpus = []solvePosition{
+	{
+		name:  "open-four",
+		rules: rules.Freestyle,
+		black: "7,6 7,7 7,8", white: "6,6 6,7",
+		color: rules.Black, vcf: true, vct: true,
+	},
+	{
+		name:  "four-three",
+		rules: rules.Freestyle,
+		black: "7,5 7,6 7,7 8,8 9,8", white: "7,4 3,3 3,4 12,12",
+		color: rules.Black, vcf: true, vct: true,
+	},
+	{
+		name:  "double-four",
+		rules: rules.Freestyle,
+		black: "7,4 7,5 7,6 4,7 5,7 6,7", white: "7,3 3,7 11,11 11,12 12,11 12,12",
+		color: rules.Black, vcf: true, vct: true,
+	},
+	{
+		// 连珠规则下黑方四四是禁手，没有 VCF，但仍有较长的 VCT
+		name:  "double-four-forbidden",
+		rules: rules.Renju,
+		black: "7,4 7,5 7,6 4,7 5,7 6,7", white: "7,3 3,7 7,8 8,7 11,11 11,12 12,11",
+		color: rules.Black, vcf: false, vct: true,
+	},
+	{
+		name:  "double-three",
+		rules: rules.Freestyle,
+		black: "7,7 7,8 8,9 9,9", white: "3,3 3,4 12,12 12,11",
+		color: rules.Black, vcf: false, vct: true,
+	},
+	{
+		// 白方没有禁手，同样的棋形在连珠规则下仍然成立
+		name:  "double-three-white",
+		rules: rules.Renju,
+		black: "3,3 3,4 12,12 12,11 0,0", white: "7,7 7,8 8,9 9,9",
+		color: rules.White, vcf: false, vct: true,
+	},
+	{
+		// 白方冲四的成五点是黑方的三三禁手，黑方挡不住
+		name:  "forbidden-block",
+		rules: rules.Renju,
+		black: "7,2 5,7 6,7 8,8 9,9", white: "7,3 7,4 7,5 0,14 14,0",
+		color: rules.White, vcf: true, vct: true, forbidden: true,
+	},
+	{
+		// 进攻方必须先挡住对方的冲四，挡的一手不构成威胁
+		name:  "must-block",
+		rules: rules.Freestyle,
+		black: "7,7 7,8 8,9 9,9 3,2", white: "3,3 3,4 3,5 3,6 12,12",
+		color: rules.Black, vcf: false, vct: false,
+	},
+	{
+		name:  "quiet",
+		rules: rules.Freestyle,
+		black: "7,7 9,9", white: "8,8 6,6",
+		color: rules.Black, vcf: false, vct: false,
+	},
+}
+
+// board 按 15 路棋盘摆放双方棋子
+func (p solvePosition) board(t testing.TB) [][]int {
+	t.Helper()
+	board := make([][]int, 15)
+	for i := range board {
+		board[i] = make([]int, 15)
+	}
+	for color, stones := range map[int]string{rules.Black: p.black, rules.White: p.white} {
+		for _, s := range strings.Fields(stones) {
+			var row, col int
+			if _, err := fmt.Sscanf(s, "%d,%d", &row, &col); err != nil {
+				t.Fatalf("%s: invalid stone %q: %v", p.name, s, err)
+			}
+			if row < 0 || row >= 15 || col < 0 || col >= 15 || board[row][col] != rules.Empty {
+				t.Fatalf("%s: invalid stone %q", p.name, s)
+			}
+			board[row][col] = color
+		}
+	}
+	return board
+}
+
+func TestSolve(t *testing.T) {
+	for _, p := range solveCorpus {
+		for _, mode := range []SolveMode{VCF, VCT} {
+			want := p.vcf
+			if mode == VCT {
+				want = p.vct
+			}
+
+			t.Run(p.name+"/"+string(mode), func(t *testing.T) {
+				ruleSet, err := rules.Get(p.rules)
+				if err != nil {
+					t.Fatal(err)
+				}
+				board := p.board(t)
+				before := copyBoard(board)
+
+				sol, err := Solve(ruleSet, board, p.color, mode, DefaultSolveConfig(mode))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(board, before) {
+					t.Fatal("Solve modified the board")
+				}
+				if sol.Found != want {
+					t.Fatalf("found = %v (aborted %v, %d nodes), want %v", sol.Found, sol.Aborted, sol.Nodes, want)
+				}
+				if sol.Found && sol.DefenderForbidden != p.forbidden {
+					t.Errorf("defender forbidden = %v, want %v", sol.DefenderForbidden, p.forbidden)
+				}
+				if sol.Found {
+					checkWinningLine(t, ruleSet, board, p.color, mode, sol)
+				} else if sol.Aborted {
+					t.Errorf("search aborted after %d nodes", sol.Nodes)
+				}
+			})
+		}
+	}
+}
+
+// checkWinningLine 在棋盘上依次摆放必胜序列，检查双方交替落在空点、进攻方不走禁手、
+// VCF 的每一手都是冲四，且以进攻方成五结束；防守方无法阻挡时最后两手都是进攻方的，成五点必须是防守方的禁手
+func checkWinningLine(t *testing.T, ruleSet rules.RuleSet, board [][]int, attacker int, mode SolveMode, sol *Solution) {
+	t.Helper()
+	line := sol.Line
+	if sol.DefenderForbidden == (len(line)%2 == 1) {
+		t.Fatalf("line %v (defender forbidden %v) does not end with an attacking move", line, sol.DefenderForbidden)
+	}
+
+	b := copyBoard(board)
+	color := attacker
+	for i, m := range line {
+		if sol.DefenderForbidden && i == len(line)-1 {
+			color = attacker
+			if f := ruleSet.CheckForbidden(b, m.Row, m.Col, opponent(attacker)); f == rules.NotForbidden {
+				t.Fatalf("defender can block the last four at %v", m)
+			}
+		}
+		if !inBoard(b, m.Row, m.Col) || b[m.Row][m.Col] != rules.Empty {
+			t.Fatalf("move %d %v is not on an empty square", i+1, m)
+		}
+		if color == attacker {
+			if f := ruleSet.CheckForbidden(b, m.Row, m.Col, color); f != rules.NotForbidden {
+				t.Fatalf("move %d %v is forbidden: %s", i+1, m, f)
+			}
+		}
+		b[m.Row][m.Col] = color
+
+		last := i == len(line)-1
+		switch {
+		case last:
+			if !ruleSet.IsWin(b, m.Row, m.Col) {
+				t.Fatalf("last move %v does not make five", m)
+			}
+		case color == attacker && mode == VCF && !hasFivePoint(ruleSet, b, attacker):
+			t.Fatalf("move %d %v is not a four", i+1, m)
+		}
+		color = opponent(color)
+	}
+}
+
+// hasFivePoint 判断 color 一方是否有能直接成五的点
+func hasFivePoint(ruleSet rules.RuleSet, b [][]int, color int) bool {
+	for r := range b {
+		for c := range b[r] {
+			if b[r][c] != rules.Empty {
+				continue
+			}
+			b[r][c] = color
+			win := ruleSet.IsWin(b, r, c)
+			b[r][c] = rules.Empty
+			if win {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func BenchmarkSolve(b *testing.B) {
+	for _, p := range solveCorpus {
+		for _, mode := range []SolveMode{VCF, VCT} {
+			b.Run(p.name+"/"+string(mode), func(b *testing.B) {
+				ruleSet, err := rules.Get(p.rules)
+				if err != nil {
+					b.Fatal(err)
+				}
+				board := p.board(b)
+
+				nodes := 0
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					sol, err := Solve(ruleSet, board, p.color, mode, DefaultSolveConfig(mode))
+					if err != nil {
+						b.Fatal(err)
+					}
+					nodes = sol.Nodes
+				}
+				b.ReportMetric(float64(nodes), "nodes/op")
+			})
+		}
+	}
+}
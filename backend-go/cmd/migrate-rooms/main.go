@@ -0,0 +1,106 @@
+// migrate-rooms 一次性迁移命令：将旧容器中按 status 分区的房间文档
+// 重写到按房间ID分区的新容器（COSMOS_CONTAINER，默认 rooms）。
+//
+// 用法:
+//
+//	go run ./cmd/migrate-rooms -from game_rooms [-dry-run] [-delete-source]
+//
+// 迁移使用 upsert，可重复执行。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+
+	"gomoku-backend/config"
+	"gomoku-backend/types"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/joho/godotenv"
+)
+
+// legacyStatuses 旧容器的全部分区
+var legacyStatuses = []string{"waiting", "playing", "finished"}
+
+func main() {
+	from := flag.String("from", "game_rooms", "legacy container partitioned by /status")
+	dryRun := flag.Bool("dry-run", false, "only report the documents that would be migrated")
+	deleteSource := flag.Bool("delete-source", false, "delete each document from the legacy container after it is migrated")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	ctx := context.Background()
+	if err := config.InitDatabase(ctx); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	target := config.GetContainer()
+	if target.ID() == *from {
+		log.Fatalf("Source and target container are both '%s', set COSMOS_CONTAINER to the new container", *from)
+	}
+
+	source, err := config.GetContainerByID(*from)
+	if err != nil {
+		log.Fatalf("Failed to open legacy container: %v", err)
+	}
+
+	migrated, failed := 0, 0
+	for _, status := range legacyStatuses {
+		partitionKey := azcosmos.NewPartitionKeyString(status)
+		queryPager := source.NewQueryItemsPager("SELECT * FROM c", partitionKey, nil)
+
+		for queryPager.More() {
+			response, err := queryPager.NextPage(ctx)
+			if err != nil {
+				log.Fatalf("Failed to query legacy partition %s: %v", status, err)
+			}
+
+			for _, item := range response.Items {
+				// 通过 GameRoom 重新序列化，去掉 _rid/_etag 等系统属性
+				var room types.GameRoom
+				if err := json.Unmarshal(item, &room); err != nil {
+					log.Printf("Skipping unreadable document in partition %s: %v", status, err)
+					failed++
+					continue
+				}
+
+				if *dryRun {
+					log.Printf("[dry-run] would migrate room %s (status %s)", room.ID, room.Status)
+					migrated++
+					continue
+				}
+
+				roomJSON, err := json.Marshal(room)
+				if err != nil {
+					log.Printf("Failed to marshal room %s: %v", room.ID, err)
+					failed++
+					continue
+				}
+
+				if _, err := target.UpsertItem(ctx, azcosmos.NewPartitionKeyString(room.ID), roomJSON, nil); err != nil {
+					log.Printf("Failed to migrate room %s: %v", room.ID, err)
+					failed++
+					continue
+				}
+
+				if *deleteSource {
+					if _, err := source.DeleteItem(ctx, partitionKey, room.ID, nil); err != nil {
+						log.Printf("Migrated room %s but failed to delete legacy document: %v", room.ID, err)
+					}
+				}
+
+				migrated++
+			}
+		}
+	}
+
+	log.Printf("Migration finished: %d migrated, %d failed", migrated, failed)
+	if failed > 0 {
+		log.Fatal("Some documents were not migrated, re-run after fixing the errors above")
+	}
+}
@@ -2,19 +2,26 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 )
 
 var (
 	cosmosClient    *azcosmos.Client
 	cosmosContainer *azcosmos.ContainerClient
+	cosmosDatabase  string
 )
 
+// RoomPartitionKeyPath 房间容器的分区键路径（按房间ID分区）
+const RoomPartitionKeyPath = "/id"
+
 // InitDatabase 初始化 Cosmos DB 连接
 func InitDatabase(ctx context.Context) error {
 	endpoint := os.Getenv("COSMOS_ENDPOINT")
@@ -26,7 +33,7 @@ func InitDatabase(ctx context.Context) error {
 		databaseID = "gomoku"
 	}
 	if containerID == "" {
-		containerID = "game_rooms"
+		containerID = "rooms"
 	}
 
 	log.Println("--- Cosmos DB Configuration ---")
@@ -34,6 +41,7 @@ func InitDatabase(ctx context.Context) error {
 	log.Printf("Key exists: %v", key != "")
 	log.Printf("Key length: %d", len(key))
 	log.Printf("Database ID: %s", databaseID)
+	log.Printf("Container ID: %s", containerID)
 	log.Println("-------------------------------")
 
 	if endpoint == "" || key == "" {
@@ -52,6 +60,7 @@ func InitDatabase(ctx context.Context) error {
 	}
 
 	cosmosClient = client
+	cosmosDatabase = databaseID
 
 	// 创建数据库（如果不存在）
 	databaseProperties := azcosmos.DatabaseProperties{ID: databaseID}
@@ -69,10 +78,10 @@ func InitDatabase(ctx context.Context) error {
 		log.Printf("Database '%s' created successfully", databaseID)
 	}
 
-	// 获取容器客户端
-	containerClient, err := client.NewContainer(databaseID, containerID)
+	// 创建容器（如果不存在），按房间ID分区
+	containerClient, err := InitContainer(ctx, containerID)
 	if err != nil {
-		return fmt.Errorf("failed to get container client: %w", err)
+		return err
 	}
 
 	cosmosContainer = containerClient
@@ -81,6 +90,40 @@ func InitDatabase(ctx context.Context) error {
 	return nil
 }
 
+// InitContainer 在当前数据库中创建容器（如果不存在）并返回其客户端，分区键为 /id
+func InitContainer(ctx context.Context, containerID string) (*azcosmos.ContainerClient, error) {
+	if cosmosClient == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	database, err := cosmosClient.NewDatabase(cosmosDatabase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database client: %w", err)
+	}
+	containerProperties := azcosmos.ContainerProperties{
+		ID: containerID,
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
+			Paths: []string{RoomPartitionKeyPath},
+		},
+	}
+	if _, err := database.CreateContainer(ctx, containerProperties, nil); err != nil {
+		if isConflict(err) {
+			log.Printf("Container '%s' already exists (this is normal)", containerID)
+		} else {
+			log.Printf("Warning: Container creation error: %v", err)
+		}
+	} else {
+		log.Printf("Container '%s' created successfully", containerID)
+	}
+
+	// 获取容器客户端
+	containerClient, err := cosmosClient.NewContainer(cosmosDatabase, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container client: %w", err)
+	}
+	return containerClient, nil
+}
+
 // GetContainer 获取容器客户端
 func GetContainer() *azcosmos.ContainerClient {
 	return cosmosContainer
@@ -90,3 +133,17 @@ func GetContainer() *azcosmos.ContainerClient {
 func GetClient() *azcosmos.Client {
 	return cosmosClient
 }
+
+// GetContainerByID 获取当前数据库中指定容器的客户端（用于迁移等运维任务）
+func GetContainerByID(containerID string) (*azcosmos.ContainerClient, error) {
+	if cosmosClient == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+	return cosmosClient.NewContainer(cosmosDatabase, containerID)
+}
+
+// isConflict 判断是否为资源已存在（409）错误
+func isConflict(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusConflict
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
-	"gomoku-backend/config"
+	"gomoku-backend/realtime"
 	"gomoku-backend/routes"
 	"gomoku-backend/services"
+	"gomoku-backend/store"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -26,17 +28,61 @@ func main() {
 		port = "3000"
 	}
 
-	// 初始化数据库
+	// 初始化数据库（STORAGE_DRIVER=memory 时使用内存存储）
 	ctx := context.Background()
-	if err := config.InitDatabase(ctx); err != nil {
+	roomStore, err := store.New(ctx)
+	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	services.SetRoomStore(roomStore)
 	log.Println("Database initialized successfully")
 
-	// 初始化 Web PubSub
-	if err := config.InitPubSub(); err != nil {
+	// 对局记录存储，对局结束时归档
+	gameStore, err := store.NewGameStore(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize game store: %v", err)
+	}
+	services.SetGameStore(gameStore)
+
+	// 对局图片和回放动画的缓存（IMAGE_CACHE_MB，默认 64）
+	if mb, err := strconv.Atoi(os.Getenv("IMAGE_CACHE_MB")); err == nil && mb >= 0 {
+		services.SetImageCacheSize(mb << 20)
+	}
+
+	// 赛后分析报告存储及分析协程（ANALYSIS_WORKERS 个，默认 2）
+	analysisStore, err := store.NewAnalysisStore(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize analysis store: %v", err)
+	}
+	services.SetAnalysisStore(analysisStore)
+	analysisWorkers := 2
+	if n, err := strconv.Atoi(os.Getenv("ANALYSIS_WORKERS")); err == nil && n > 0 {
+		analysisWorkers = n
+	}
+	services.StartAnalysisWorkers(analysisWorkers, 100)
+
+	// 同时进行的局面分析、提示和解题搜索数（SEARCH_CONCURRENCY，默认为 CPU 核数的一半）
+	if n, err := strconv.Atoi(os.Getenv("SEARCH_CONCURRENCY")); err == nil && n > 0 {
+		services.SetSearchConcurrency(n)
+	}
+
+	// 外部引擎（PISKVORK_ENGINES=名称=命令,...），每步限时 PISKVORK_TURN_TIMEOUT（默认 5s）
+	if spec := os.Getenv("PISKVORK_ENGINES"); spec != "" {
+		engines, err := services.ParseExternalEngines(spec)
+		if err != nil {
+			log.Fatalf("Invalid PISKVORK_ENGINES: %v", err)
+		}
+		turnTimeout, _ := time.ParseDuration(os.Getenv("PISKVORK_TURN_TIMEOUT"))
+		services.SetExternalEngines(engines, turnTimeout)
+		log.Printf("External engines: %v", services.ExternalEngineNames())
+	}
+
+	// 初始化 Web PubSub（REALTIME_DRIVER=websocket 时使用内置 WebSocket 服务）
+	broadcaster, err := realtime.New()
+	if err != nil {
 		log.Fatalf("Failed to initialize PubSub: %v", err)
 	}
+	services.SetBroadcaster(broadcaster)
 	log.Println("PubSub initialized successfully")
 
 	// 创建 Gin 路由器
@@ -66,7 +112,7 @@ func main() {
 	})
 
 	// 注册路由
-	routes.RegisterRoutes(router)
+	routes.RegisterRoutes(router, broadcaster)
 
 	// 启动定期清理任务
 	go func() {
@@ -79,6 +125,18 @@ func main() {
 		}
 	}()
 
+	// 启动房间定时任务检查（对局超时、多局制比赛的下一局），恢复重启前的定时器并补偿其他实例
+	// 本实例的到期事件由房间定时器及时处理，这里只是低频的补偿扫描（跨分区查询）
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+		for ; true; <-ticker.C {
+			if err := services.CheckRoomTimers(ctx); err != nil {
+				log.Printf("Error in room timer task: %v", err)
+			}
+		}
+	}()
+
 	// 启动服务器
 	log.Printf("Server is running on port %s", port)
 	log.Printf("Environment: %s", os.Getenv("NODE_ENV"))
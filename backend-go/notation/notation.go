@@ -0,0 +1,145 @@
+// Package notation 对局记录的文件格式：Gomocup PSQ、SGF（GM[4]）和连珠记谱（h8 i9 ...）。
+//
+// 坐标约定与房间一致：Row 0 为棋盘最上方一行，Col 0 为最左一列。
+package notation
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// 文件格式
+const (
+	PSQ   = "psq"   // Gomocup/Piskvork 对局文件，Yixin 等引擎界面可直接打开
+	SGF   = "sgf"   // Smart Game Format，GM[4] 表示五子棋
+	Renju = "renju" // 连珠记谱：标签头加 "h8 i9 ..." 着法列表
+)
+
+// 对局结束原因，与 GameResult.Reason 相同
+const (
+	reasonFive      = "five"
+	reasonDraw      = "draw"
+	reasonForbidden = "forbidden-move"
+	reasonTimeout   = "timeout"
+	reasonResign    = "resign"
+)
+
+// Record 导出或导入的一局棋
+type Record struct {
+	Event     string // 对局名称，如房间号和局数
+	BoardSize int
+	Rules     string
+	Black     string // 黑方名称
+	White     string // 白方名称
+	Moves     []types.Move
+	Winner    int    // 1 黑胜，2 白胜，0 平局或未分胜负
+	EndReason string // five, draw, forbidden-move, timeout, resign, abandon，为空表示未结束
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// Export 按 format 输出对局记录
+func Export(rec *Record, format string) ([]byte, error) {
+	switch format {
+	case PSQ:
+		return writePSQ(rec), nil
+	case SGF:
+		return writeSGF(rec), nil
+	case Renju:
+		return writeRenju(rec), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// ParseError 对局记录解析错误，Move 为出错的着法序号（从 1 开始），0 表示文件头或整体格式错误
+type ParseError struct {
+	Move int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Move > 0 {
+		return fmt.Sprintf("move %d: %s", e.Move, e.Msg)
+	}
+	return e.Msg
+}
+
+// moveError 返回第 n 步的解析错误
+func moveError(n int, format string, args ...interface{}) error {
+	return &ParseError{Move: n, Msg: fmt.Sprintf(format, args...)}
+}
+
+// headerError 返回文件头的解析错误
+func headerError(format string, args ...interface{}) error {
+	return &ParseError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// Parse 按 format 解析对局记录，format 为空时根据内容判断
+//
+// 文件没有给出棋盘大小时使用 defaultSize。没有写明执子颜色的着法从黑方开始交替；
+// 只解析着法和文件头，是否符合规则由调用方校验。
+func Parse(data []byte, format string, defaultSize int) (*Record, error) {
+	if format == "" {
+		format = Detect(data)
+	}
+	switch format {
+	case PSQ:
+		return parsePSQ(data)
+	case SGF:
+		return parseSGF(data, defaultSize)
+	case Renju:
+		return parseRenju(data, defaultSize)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// Detect 根据内容判断格式：以 "(" 开头为 SGF，以 "Piskvorky" 开头为 PSQ，其余按连珠记谱处理
+func Detect(data []byte) string {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("(")):
+		return SGF
+	case bytes.HasPrefix(trimmed, []byte("Piskvorky")):
+		return PSQ
+	}
+	return Renju
+}
+
+// alternate 为没有写明颜色的着法按黑白交替补上颜色
+func alternate(moves []types.Move) {
+	for i := range moves {
+		moves[i].Player = 1 + i%2
+	}
+}
+
+// ContentType 返回格式对应的 MIME 类型
+func ContentType(format string) string {
+	if format == SGF {
+		return "application/x-go-sgf; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Extension 返回格式对应的文件扩展名
+func Extension(format string) string {
+	if format == Renju {
+		return "txt"
+	}
+	return format
+}
+
+// colorName 返回执子方的名称，未设置时使用颜色
+func (rec *Record) colorName(color int) string {
+	switch {
+	case color == 1 && rec.Black != "":
+		return rec.Black
+	case color == 2 && rec.White != "":
+		return rec.White
+	case color == 1:
+		return "Black"
+	}
+	return "White"
+}
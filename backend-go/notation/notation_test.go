@@ -0,0 +1,213 @@
+package notation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// parseMoves 把 "行,列 行,列 ..." 转换为黑白交替的着法
+func parseMoves(t *testing.T, s string) []types.Move {
+	t.Helper()
+	var moves []types.Move
+	for i, field := range strings.Fields(s) {
+		var m types.Move
+		if _, err := fmt.Sscanf(field, "%d,%d", &m.Row, &m.Col); err != nil {
+			t.Fatalf("invalid move %q: %v", field, err)
+		}
+		m.Player = 1 + i%2
+		moves = append(moves, m)
+	}
+	return moves
+}
+
+// record 一局已结束的对局，着法含边角的点，名称含需要转义的字符
+func record(t *testing.T) *Record {
+	t.Helper()
+	start := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	end := start.Add(25 * time.Minute)
+	return &Record{
+		Event:     `Room 12 "final" [game 3]`,
+		BoardSize: 15,
+		Rules:     "renju",
+		Black:     `ali\ce`,
+		White:     "bob]",
+		Moves:     parseMoves(t, "7,7 7,8 8,8 0,0 9,9 14,14 6,6 0,14 10,10 14,0 11,11"),
+		Winner:    1,
+		EndReason: reasonFive,
+		StartTime: &start,
+		EndTime:   &end,
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		format string
+		want   func(rec *Record) // 去掉格式无法表示的字段
+	}{
+		{
+			format: PSQ,
+			want: func(rec *Record) {
+				rec.Event, rec.Rules = "", ""
+				rec.Winner, rec.EndReason = 0, ""
+				rec.StartTime, rec.EndTime = nil, nil
+			},
+		},
+		{
+			format: SGF,
+			want: func(rec *Record) {
+				// 只保留开始日期，结束时间写在注释中
+				date := time.Date(rec.StartTime.Year(), rec.StartTime.Month(), rec.StartTime.Day(), 0, 0, 0, 0, time.UTC)
+				rec.StartTime, rec.EndTime = &date, nil
+			},
+		},
+		{
+			format: Renju,
+			want:   func(rec *Record) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := Export(record(t), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Detect(data); got != tt.format {
+				t.Errorf("Detect = %q, want %q", got, tt.format)
+			}
+
+			got, err := Parse(data, "", 19)
+			if err != nil {
+				t.Fatalf("Parse: %v\n%s", err, data)
+			}
+			want := record(t)
+			tt.want(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip:\n got %+v\nwant %+v\n%s", got, want, data)
+			}
+		})
+	}
+}
+
+func TestRoundTripEndReasons(t *testing.T) {
+	// PSQ 没有结果字段；SGF 的 RE 区分认输、超时和禁手，其余结束原因记为五连
+	tests := []struct {
+		winner int
+		reason string
+		sgf    string // 从 SGF 读回的结束原因
+	}{
+		{winner: 1, reason: reasonFive, sgf: reasonFive},
+		{winner: 2, reason: reasonResign, sgf: reasonResign},
+		{winner: 1, reason: reasonTimeout, sgf: reasonTimeout},
+		{winner: 2, reason: reasonForbidden, sgf: reasonForbidden},
+		{winner: 0, reason: reasonDraw, sgf: reasonDraw},
+		{winner: 2, reason: "abandon", sgf: reasonFive},
+		{winner: 0, reason: "", sgf: ""},
+	}
+
+	for _, tt := range tests {
+		for format, want := range map[string]string{SGF: tt.sgf, Renju: tt.reason} {
+			t.Run(format+"/"+tt.reason, func(t *testing.T) {
+				rec := record(t)
+				rec.Winner, rec.EndReason = tt.winner, tt.reason
+				data, err := Export(rec, format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := Parse(data, format, 15)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Winner != tt.winner || got.EndReason != want {
+					t.Errorf("result = %d %q, want %d %q", got.Winner, got.EndReason, tt.winner, want)
+				}
+			})
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		move   int // 出错的着法序号，0 表示文件头错误
+	}{
+		{
+			name:   "psq bad move",
+			format: PSQ,
+			data:   "Piskvorky 15x15, 11:11, 0\n8,8,0\n9,x,0\n-1\n",
+			move:   2,
+		},
+		{
+			name:   "psq bad header",
+			format: PSQ,
+			data:   "Piskvorky 15x20, 11:11, 0\n8,8,0\n",
+		},
+		{
+			name:   "sgf point outside the board",
+			format: SGF,
+			data:   "(;GM[4]SZ[15];B[hh];W[hi];B[zz])",
+			move:   3,
+		},
+		{
+			name:   "sgf pass",
+			format: SGF,
+			data:   "(;GM[4]SZ[15];B[hh];W[])",
+			move:   2,
+		},
+		{
+			name:   "sgf unterminated value",
+			format: SGF,
+			data:   "(;GM[4]SZ[15];B[hh];W[hi",
+			move:   2,
+		},
+		{
+			name:   "sgf not gomoku",
+			format: SGF,
+			data:   "(;GM[1]SZ[19];B[pd])",
+		},
+		{
+			name:   "renju bad square",
+			format: Renju,
+			data:   "1. h8 i9 2. j10 k11 3. q3",
+			move:   5,
+		},
+		{
+			name:   "renju unterminated comment",
+			format: Renju,
+			data:   "1. h8 i9 {good 2. j10",
+			move:   3,
+		},
+		{
+			name:   "renju bad board size",
+			format: Renju,
+			data:   "[BoardSize \"big\"]\n\nh8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), tt.format, 15)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("err = %v, want a ParseError", err)
+			}
+			if perr.Move != tt.move {
+				t.Errorf("error %q is for move %d, want %d", err, perr.Move, tt.move)
+			}
+			switch prefix := fmt.Sprintf("move %d: ", tt.move); {
+			case tt.move > 0 && !strings.HasPrefix(err.Error(), prefix):
+				t.Errorf("error %q does not start with %q", err, prefix)
+			case tt.move == 0 && strings.HasPrefix(err.Error(), "move "):
+				t.Errorf("header error %q names a move", err)
+			}
+		})
+	}
+}
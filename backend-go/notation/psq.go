@@ -0,0 +1,83 @@
+package notation
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gomoku-backend/types"
+)
+
+// writePSQ 输出 Gomocup PSQ 格式
+//
+// 首行为 "Piskvorky 15x15, 11:11, 0"，每步一行 "x,y,用时毫秒"（x 为列、y 为行，从 1 开始），
+// 着法之后是先手和后手的名称，最后一行为 -1。格式没有规则、结果和时间的字段。
+// 用时按落子时间与上一步（第一步为开局时间）之差计算，没有落子时间时为 0。
+func writePSQ(rec *Record) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Piskvorky %dx%d, 11:11, 0\n", rec.BoardSize, rec.BoardSize)
+	prev := rec.StartTime
+	for _, m := range rec.Moves {
+		var elapsed int64
+		if m.Time != nil && prev != nil {
+			elapsed = max(0, m.Time.Sub(*prev).Milliseconds())
+		}
+		prev = m.Time
+		fmt.Fprintf(&buf, "%d,%d,%d\n", m.Col+1, m.Row+1, elapsed)
+	}
+	fmt.Fprintf(&buf, "%s\n%s\n-1\n", rec.colorName(1), rec.colorName(2))
+	return buf.Bytes()
+}
+
+// parsePSQ 解析 Gomocup PSQ 格式，着法之后的两行（若有）为先手和后手的名称
+func parsePSQ(data []byte) (*Record, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	header := strings.TrimSpace(strings.TrimPrefix(lines[0], "\xef\xbb\xbf"))
+
+	var width, height int
+	if _, err := fmt.Sscanf(header, "Piskvorky %dx%d", &width, &height); err != nil {
+		return nil, headerError("invalid PSQ header %q", header)
+	}
+	if width != height {
+		return nil, headerError("only square boards are supported, got %dx%d", width, height)
+	}
+
+	rec := &Record{BoardSize: width}
+	i := 1
+	for ; i < len(lines); i++ {
+		// 不以数字开头的行是着法之后的名称
+		line := strings.TrimSpace(lines[i])
+		fields := strings.Split(line, ",")
+		x, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil || line == "-1" {
+			break
+		}
+		n := len(rec.Moves) + 1
+		if len(fields) < 2 {
+			return nil, moveError(n, "cannot parse %q", line)
+		}
+		y, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return nil, moveError(n, "cannot parse %q", line)
+		}
+		rec.Moves = append(rec.Moves, types.Move{Row: y - 1, Col: x - 1})
+	}
+	alternate(rec.Moves)
+
+	// 着法之后依次为先手、后手名称，-1 或空行结束
+	var names []string
+	for ; i < len(lines) && len(names) < 2; i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "-1" {
+			break
+		}
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+	if len(names) == 2 {
+		rec.Black, rec.White = names[0], names[1]
+	}
+	return rec, nil
+}
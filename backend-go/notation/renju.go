@@ -0,0 +1,223 @@
+package notation
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// writeRenju 输出连珠记谱：PGN 风格的标签头，空行后是 "h8 i9 ..." 着法列表和结果
+//
+// 列用字母 a 起从左到右，行用数字 1 起从下到上，15 路棋盘的天元为 h8。
+func writeRenju(rec *Record) []byte {
+	var buf bytes.Buffer
+	tag := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "[%s \"%s\"]\n", name, strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value))
+		}
+	}
+
+	tag("Event", rec.Event)
+	tag("Black", rec.colorName(1))
+	tag("White", rec.colorName(2))
+	tag("Rules", rec.Rules)
+	tag("BoardSize", fmt.Sprint(rec.BoardSize))
+	if rec.StartTime != nil {
+		tag("Date", rec.StartTime.UTC().Format("2006.01.02"))
+		tag("StartTime", rec.StartTime.UTC().Format(time.RFC3339))
+	}
+	if rec.EndTime != nil {
+		tag("EndTime", rec.EndTime.UTC().Format(time.RFC3339))
+	}
+	tag("Result", renjuResult(rec))
+	tag("Termination", rec.EndReason)
+	buf.WriteString("\n")
+
+	moves := make([]string, len(rec.Moves))
+	for i, m := range rec.Moves {
+		moves[i] = renjuSquare(rec.BoardSize, m.Row, m.Col)
+	}
+	if result := renjuResult(rec); result != "*" {
+		moves = append(moves, result)
+	}
+	buf.WriteString(strings.Join(moves, " "))
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// renjuSquare 返回坐标的记谱表示，如 h8
+func renjuSquare(size, row, col int) string {
+	return fmt.Sprintf("%c%d", 'a'+col, size-row)
+}
+
+// renjuResult 返回结果标记：1-0 黑胜，0-1 白胜，1/2-1/2 和棋，* 未结束
+func renjuResult(rec *Record) string {
+	switch {
+	case rec.EndReason == "":
+		return "*"
+	case rec.Winner == 1:
+		return "1-0"
+	case rec.Winner == 2:
+		return "0-1"
+	}
+	return "1/2-1/2"
+}
+
+// parseRenju 解析连珠记谱：可选的 [Tag "value"] 标签行，之后是空白分隔的着法，
+// 着法序号（如 "1."）和结果标记会被忽略，花括号内为注释
+func parseRenju(data []byte, defaultSize int) (*Record, error) {
+	rec := &Record{BoardSize: defaultSize}
+	var body strings.Builder
+	for _, line := range strings.Split(strings.TrimPrefix(string(data), "\xef\xbb\xbf"), "\n") {
+		line = strings.TrimSpace(line)
+		if name, value, ok := renjuTag(line); ok {
+			if err := rec.setTag(name, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		body.WriteString(line)
+		body.WriteString(" ")
+	}
+
+	// 未闭合的注释之后不再解析，报告为注释前最后一步的下一步
+	text, unterminated := body.String(), false
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			text, unterminated = text[:start], true
+			break
+		}
+		text = text[:start] + " " + text[start+end+1:]
+	}
+
+	for _, token := range strings.Fields(text) {
+		// 去掉着法序号，如 "1." 或 "1.h8"
+		if i := strings.LastIndexByte(token, '.'); i >= 0 && isDigits(token[:i]) {
+			token = token[i+1:]
+		}
+		switch token {
+		case "", "*":
+			continue
+		case "1-0", "0-1", "1/2-1/2":
+			if rec.EndReason == "" {
+				rec.Winner, rec.EndReason = parseRenjuResult(token)
+			}
+			continue
+		}
+
+		n := len(rec.Moves) + 1
+		m, err := parseRenjuSquare(strings.ToLower(token), rec.BoardSize)
+		if err != nil {
+			return nil, moveError(n, "%v", err)
+		}
+		rec.Moves = append(rec.Moves, m)
+	}
+	if unterminated {
+		return nil, moveError(len(rec.Moves)+1, "unterminated comment")
+	}
+	alternate(rec.Moves)
+	return rec, nil
+}
+
+// renjuTag 解析 [Name "value"] 标签行
+func renjuTag(line string) (name, value string, ok bool) {
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return "", "", false
+	}
+	name, quoted, found := strings.Cut(line[1:len(line)-1], " ")
+	if !found {
+		return "", "", false
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(quoted))
+	if err != nil {
+		return "", "", false
+	}
+	return name, value, true
+}
+
+// setTag 根据标签设置对局信息，未知标签忽略
+func (rec *Record) setTag(name, value string) error {
+	switch name {
+	case "Event":
+		rec.Event = value
+	case "Black":
+		rec.Black = value
+	case "White":
+		rec.White = value
+	case "Rules":
+		rec.Rules = value
+	case "BoardSize":
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return headerError("invalid BoardSize %q", value)
+		}
+		rec.BoardSize = size
+	case "StartTime", "EndTime":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return headerError("invalid %s %q", name, value)
+		}
+		if name == "StartTime" {
+			rec.StartTime = &t
+		} else {
+			rec.EndTime = &t
+		}
+	case "Result":
+		winner, reason := parseRenjuResult(value)
+		rec.Winner = winner
+		if rec.EndReason == "" {
+			rec.EndReason = reason
+		}
+	case "Termination":
+		rec.EndReason = value
+	}
+	return nil
+}
+
+// parseRenjuSquare 解析记谱坐标，如 h8
+func parseRenjuSquare(token string, size int) (types.Move, error) {
+	if len(token) < 2 || token[0] < 'a' || token[0] > 'z' || !isDigits(token[1:]) {
+		return types.Move{}, fmt.Errorf("cannot parse %q", token)
+	}
+	col := int(token[0] - 'a')
+	number, _ := strconv.Atoi(token[1:])
+	if col >= size || number < 1 || number > size {
+		return types.Move{}, fmt.Errorf("%s is outside the %dx%d board", token, size, size)
+	}
+	return types.Move{Row: size - number, Col: col}, nil
+}
+
+// parseRenjuResult 返回结果标记对应的胜方颜色和结束原因，未结束时原因为空
+func parseRenjuResult(result string) (int, string) {
+	switch result {
+	case "1-0":
+		return 1, reasonFive
+	case "0-1":
+		return 2, reasonFive
+	case "1/2-1/2":
+		return 0, reasonDraw
+	}
+	return 0, ""
+}
+
+// isDigits 判断字符串是否非空且只含数字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
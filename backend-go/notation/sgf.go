@@ -0,0 +1,348 @@
+package notation
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// writeSGF 输出 SGF（FF[4]，GM[4]）格式，坐标为两个小写字母：列在前、行在后，aa 为左上角
+func writeSGF(rec *Record) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("(;GM[4]FF[4]CA[UTF-8]AP[gomoku-backend]")
+	fmt.Fprintf(&buf, "SZ[%d]", rec.BoardSize)
+	if rec.Rules != "" {
+		fmt.Fprintf(&buf, "RU[%s]", sgfText(rec.Rules))
+	}
+	if rec.Event != "" {
+		fmt.Fprintf(&buf, "EV[%s]", sgfText(rec.Event))
+	}
+	fmt.Fprintf(&buf, "PB[%s]PW[%s]", sgfText(rec.colorName(1)), sgfText(rec.colorName(2)))
+	if rec.StartTime != nil {
+		fmt.Fprintf(&buf, "DT[%s]", rec.StartTime.UTC().Format("2006-01-02"))
+	}
+	if result := sgfResult(rec); result != "" {
+		fmt.Fprintf(&buf, "RE[%s]", result)
+	}
+	if comment := timeComment(rec); comment != "" {
+		fmt.Fprintf(&buf, "C[%s]", sgfText(comment))
+	}
+	buf.WriteString("\n")
+
+	for i, m := range rec.Moves {
+		color := "B"
+		if m.Player == 2 {
+			color = "W"
+		}
+		fmt.Fprintf(&buf, ";%s[%c%c]", color, 'a'+m.Col, 'a'+m.Row)
+		if (i+1)%10 == 0 {
+			buf.WriteString("\n")
+		}
+	}
+	buf.WriteString(")\n")
+	return buf.Bytes()
+}
+
+// sgfResult 返回 RE 属性：B+/W+ 后接 R（认输）、T（超时）、F（禁手判负），和棋为 0，未结束时为空
+func sgfResult(rec *Record) string {
+	if rec.EndReason == "" {
+		return ""
+	}
+	if rec.Winner == 0 {
+		return "0"
+	}
+	result := "B+"
+	if rec.Winner == 2 {
+		result = "W+"
+	}
+	switch rec.EndReason {
+	case reasonResign:
+		result += "R"
+	case reasonTimeout:
+		result += "T"
+	case reasonForbidden:
+		result += "F"
+	}
+	return result
+}
+
+// timeComment 返回对局起止时间的说明，没有时间时为空
+func timeComment(rec *Record) string {
+	var parts []string
+	if rec.StartTime != nil {
+		parts = append(parts, "Start: "+rec.StartTime.UTC().Format(time.RFC3339))
+	}
+	if rec.EndTime != nil {
+		parts = append(parts, "End: "+rec.EndTime.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// sgfText 转义 SGF 属性值中的 ] 和 \
+func sgfText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `]`, `\]`).Replace(s)
+}
+
+// sgfNode SGF 节点的属性（属性名 -> 值）
+type sgfNode map[string][]string
+
+// sgfParser SGF 语法解析状态
+type sgfParser struct {
+	s     string
+	pos   int
+	moves int // 已解析的着法节点数，用于报告出错的着法序号
+}
+
+// parseSGF 解析 SGF（GM[4]）对局记录，只取主线；根节点的 AB/AW 摆子按黑白交替转换为着法
+func parseSGF(data []byte, defaultSize int) (*Record, error) {
+	p := &sgfParser{s: strings.TrimPrefix(string(data), "\xef\xbb\xbf")}
+	p.skipSpace()
+	if !p.consume('(') {
+		return nil, headerError("invalid SGF: expected '('")
+	}
+	nodes, err := p.sequence()
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, headerError("invalid SGF: empty game tree")
+	}
+
+	root := nodes[0]
+	if gm := root.first("GM"); gm != "" && gm != "4" {
+		return nil, headerError("not a gomoku record (GM[%s])", gm)
+	}
+	rec := &Record{
+		BoardSize: defaultSize,
+		Rules:     root.first("RU"),
+		Event:     root.first("EV"),
+		Black:     root.first("PB"),
+		White:     root.first("PW"),
+	}
+	if sz := root.first("SZ"); sz != "" {
+		size, err := strconv.Atoi(strings.SplitN(sz, ":", 2)[0])
+		if err != nil {
+			return nil, headerError("invalid board size SZ[%s]", sz)
+		}
+		rec.BoardSize = size
+	}
+	if dt := root.first("DT"); len(dt) >= 10 {
+		if t, err := time.Parse("2006-01-02", dt[:10]); err == nil {
+			rec.StartTime = &t
+		}
+	}
+	rec.Winner, rec.EndReason = parseSGFResult(root.first("RE"))
+
+	// 摆子：黑白数量相同或黑方多一子时按交替顺序转换为着法
+	black, white := root["AB"], root["AW"]
+	if len(black) != len(white) && len(black) != len(white)+1 {
+		return nil, headerError("setup stones AB/AW cannot be converted to alternating moves (%d black, %d white)", len(black), len(white))
+	}
+	for i := range black {
+		for color, points := range [][]string{black, white} {
+			if i >= len(points) {
+				break
+			}
+			m, err := sgfPoint(points[i], rec.BoardSize)
+			if err != nil {
+				return nil, moveError(len(rec.Moves)+1, "%v", err)
+			}
+			m.Player = color + 1
+			rec.Moves = append(rec.Moves, m)
+		}
+	}
+
+	for _, node := range nodes {
+		for color, prop := range []string{"B", "W"} {
+			values, ok := node[prop]
+			if !ok {
+				continue
+			}
+			n := len(rec.Moves) + 1
+			if len(values) != 1 {
+				return nil, moveError(n, "expected one point in %s", prop)
+			}
+			m, err := sgfPoint(values[0], rec.BoardSize)
+			if err != nil {
+				return nil, moveError(n, "%v", err)
+			}
+			m.Player = color + 1
+			rec.Moves = append(rec.Moves, m)
+		}
+	}
+	return rec, nil
+}
+
+// sgfPoint 解析 SGF 坐标，空值或 tt 表示停一手，五子棋不支持
+func sgfPoint(value string, size int) (types.Move, error) {
+	if value == "" || (value == "tt" && size <= 19) {
+		return types.Move{}, fmt.Errorf("pass is not supported")
+	}
+	if len(value) != 2 || value[0] < 'a' || value[1] < 'a' || int(value[0]-'a') >= size || int(value[1]-'a') >= size {
+		return types.Move{}, fmt.Errorf("invalid point [%s] on a %dx%d board", value, size, size)
+	}
+	return types.Move{Row: int(value[1] - 'a'), Col: int(value[0] - 'a')}, nil
+}
+
+// parseSGFResult 解析 RE 属性，返回胜方颜色和结束原因
+func parseSGFResult(re string) (int, string) {
+	switch {
+	case re == "0" || strings.EqualFold(re, "draw"):
+		return 0, reasonDraw
+	case len(re) < 2 || re[1] != '+' || (re[0] != 'B' && re[0] != 'W'):
+		return 0, ""
+	}
+
+	winner := 1
+	if re[0] == 'W' {
+		winner = 2
+	}
+	switch strings.ToUpper(re[2:]) {
+	case "R", "RESIGN":
+		return winner, reasonResign
+	case "T", "TIME":
+		return winner, reasonTimeout
+	case "F", "FORFEIT":
+		return winner, reasonForbidden
+	}
+	return winner, reasonFive
+}
+
+// first 返回属性的第一个值，不存在时为空
+func (n sgfNode) first(prop string) string {
+	if values := n[prop]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// sequence 解析一个变化中的节点序列，遇到子变化时只保留第一个
+func (p *sgfParser) sequence() ([]sgfNode, error) {
+	var nodes []sgfNode
+	for {
+		p.skipSpace()
+		switch {
+		case p.consume(';'):
+			node, err := p.node()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		case p.consume('('):
+			main, err := p.sequence()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, main...)
+			// 跳过其余变化
+			for p.skipSpace(); p.consume('('); p.skipSpace() {
+				saved := p.moves
+				if _, err := p.sequence(); err != nil {
+					return nil, err
+				}
+				p.moves = saved
+			}
+			if !p.consume(')') {
+				return nil, p.errorf("expected ')'")
+			}
+			return nodes, nil
+		case p.consume(')'):
+			return nodes, nil
+		default:
+			return nil, p.errorf("unexpected end of game tree")
+		}
+	}
+}
+
+// node 解析一个节点的属性
+func (p *sgfParser) node() (sgfNode, error) {
+	node := sgfNode{}
+	for {
+		p.skipSpace()
+		start := p.pos
+		for p.pos < len(p.s) && isLetter(p.s[p.pos]) {
+			p.pos++
+		}
+		if start == p.pos {
+			return node, nil
+		}
+		// FF[3] 允许属性名中夹杂小写字母，只保留大写部分
+		var ident strings.Builder
+		for _, r := range p.s[start:p.pos] {
+			if r >= 'A' && r <= 'Z' {
+				ident.WriteRune(r)
+			}
+		}
+		prop := ident.String()
+		if prop == "B" || prop == "W" {
+			p.moves++
+		}
+
+		p.skipSpace()
+		if p.pos >= len(p.s) || p.s[p.pos] != '[' {
+			return nil, p.errorf("expected value for property %s", prop)
+		}
+		for p.skipSpace(); p.consume('['); p.skipSpace() {
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			node[prop] = append(node[prop], value)
+		}
+	}
+}
+
+// value 解析属性值直到未转义的 ]
+func (p *sgfParser) value() (string, error) {
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch c {
+		case '\\':
+			if p.pos < len(p.s) {
+				b.WriteByte(p.s[p.pos])
+				p.pos++
+			}
+		case ']':
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated property value")
+}
+
+// errorf 返回语法错误，已经开始解析着法时报告当前着法序号
+func (p *sgfParser) errorf(format string, args ...interface{}) error {
+	msg := fmt.Sprintf("invalid SGF at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+	if p.moves > 0 {
+		return &ParseError{Move: p.moves, Msg: msg}
+	}
+	return &ParseError{Msg: msg}
+}
+
+// consume 跳过期望的字符
+func (p *sgfParser) consume(c byte) bool {
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// skipSpace 跳过空白
+func (p *sgfParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// isLetter 判断是否为属性名字符
+func isLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
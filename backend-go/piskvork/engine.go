@@ -0,0 +1,317 @@
+// Package piskvork 通过标准输入输出驱动实现 Piskvork（Gomocup）协议的外部五子棋引擎进程。
+//
+// 协议是按行的文本命令：START 初始化棋盘，INFO 设置时间等参数，BEGIN/TURN/BOARD 要求引擎落子，
+// 引擎以 "x,y" 回复（x 为列，y 为行，从 0 开始），END 结束进程。
+// 引擎输出的 MESSAGE、DEBUG 等行只记录日志。
+package piskvork
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTimeout 引擎未在限时内回复，进程已被终止
+	ErrTimeout = errors.New("engine timed out")
+	// ErrExited 引擎进程已退出
+	ErrExited = errors.New("engine exited")
+	// ErrProtocol 引擎回复了 ERROR、UNKNOWN 或无法解析的内容
+	ErrProtocol = errors.New("engine protocol error")
+)
+
+// 棋盘上的棋子归属，用于 BOARD 命令
+const (
+	fieldOwn      = 1
+	fieldOpponent = 2
+)
+
+// Config 引擎进程参数
+type Config struct {
+	Command      string        // 可执行文件路径
+	Args         []string      // 命令行参数
+	Dir          string        // 工作目录，为空时使用当前目录
+	BoardSize    int           // START 的棋盘边长
+	Rule         int           // INFO rule 位掩码：1 恰好五连，4 连珠，8 两端被堵的五连不算（caro）
+	TurnTimeout  time.Duration // INFO timeout_turn，每步的时间限制
+	MaxMemory    int64         // INFO max_memory（字节），0 表示不限
+	StartTimeout time.Duration // 等待 START 回复 OK 的时间，默认 10 秒
+	Grace        time.Duration // 超过每步限时后额外等待的时间，用于抵消进程调度和管道延迟，默认 1 秒
+}
+
+// Move 落子点
+type Move struct {
+	Row int
+	Col int
+}
+
+// Stone 已有的棋子，Own 表示是否为引擎一方的棋子
+type Stone struct {
+	Move
+	Own bool
+}
+
+// Engine 运行中的引擎进程，命令串行执行，可并发调用
+type Engine struct {
+	cfg    Config
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan string   // 引擎的回复行，进程输出结束时关闭
+	exited chan struct{} // 进程退出时关闭
+
+	mu sync.Mutex
+}
+
+// Start 启动引擎进程并发送 START 和 INFO，引擎回复 OK 后返回
+func Start(cfg Config) (*Engine, error) {
+	if cfg.StartTimeout <= 0 {
+		cfg.StartTimeout = 10 * time.Second
+	}
+	if cfg.Grace <= 0 {
+		cfg.Grace = time.Second
+	}
+
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Dir = cfg.Dir
+	cmd.Stderr = &logWriter{prefix: cfg.Command}
+	setProcessGroup(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start engine %s: %w", cfg.Command, err)
+	}
+
+	e := &Engine{
+		cfg:    cfg,
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan string, 16),
+		exited: make(chan struct{}),
+	}
+	go e.readLoop(stdout)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.send(fmt.Sprintf("START %d", cfg.BoardSize)); err != nil {
+		e.kill()
+		return nil, err
+	}
+	reply, err := e.readReply(cfg.StartTimeout)
+	if err != nil {
+		e.kill()
+		return nil, err
+	}
+	if reply != "OK" {
+		e.kill()
+		return nil, fmt.Errorf("%w: START %d: %s", ErrProtocol, cfg.BoardSize, reply)
+	}
+
+	infos := []string{fmt.Sprintf("INFO rule %d", cfg.Rule)}
+	if cfg.TurnTimeout > 0 {
+		infos = append(infos, fmt.Sprintf("INFO timeout_turn %d", cfg.TurnTimeout.Milliseconds()))
+	}
+	if cfg.MaxMemory > 0 {
+		infos = append(infos, fmt.Sprintf("INFO max_memory %d", cfg.MaxMemory))
+	}
+	if err := e.send(infos...); err != nil {
+		e.kill()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Info 发送 INFO 命令，引擎不回复
+func (e *Engine) Info(key string, value interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.send(fmt.Sprintf("INFO %s %v", key, value))
+}
+
+// Begin 在空棋盘上让引擎先行
+func (e *Engine) Begin(timeout time.Duration) (Move, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.request(timeout, "BEGIN")
+}
+
+// Turn 告知对方的落子并让引擎回应
+func (e *Engine) Turn(opponent Move, timeout time.Duration) (Move, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.request(timeout, fmt.Sprintf("TURN %d,%d", opponent.Col, opponent.Row))
+}
+
+// Board 发送完整局面（按落子顺序）并让引擎落子，悔棋、换局后用它重新同步引擎的棋盘
+func (e *Engine) Board(stones []Stone, timeout time.Duration) (Move, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cmds := make([]string, 0, len(stones)+2)
+	cmds = append(cmds, "BOARD")
+	for _, s := range stones {
+		field := fieldOpponent
+		if s.Own {
+			field = fieldOwn
+		}
+		cmds = append(cmds, fmt.Sprintf("%d,%d,%d", s.Col, s.Row, field))
+	}
+	cmds = append(cmds, "DONE")
+	return e.request(timeout, cmds...)
+}
+
+// Alive 判断进程是否仍在运行
+func (e *Engine) Alive() bool {
+	select {
+	case <-e.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close 发送 END 并等待进程退出，超过 1 秒仍未退出时强制终止
+func (e *Engine) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.Alive() {
+		return
+	}
+	_ = e.send("END")
+	_ = e.stdin.Close()
+	select {
+	case <-e.exited:
+	case <-time.After(time.Second):
+		e.kill()
+	}
+}
+
+// request 发送命令并等待落子回复，timeout 为本步限时（不含 Grace），超时后终止进程
+func (e *Engine) request(timeout time.Duration, cmds ...string) (Move, error) {
+	e.drain()
+	if err := e.send(cmds...); err != nil {
+		return Move{}, err
+	}
+	if timeout <= 0 {
+		timeout = e.cfg.TurnTimeout
+	}
+	reply, err := e.readReply(timeout + e.cfg.Grace)
+	if err != nil {
+		if errors.Is(err, ErrTimeout) {
+			e.kill()
+		}
+		return Move{}, err
+	}
+
+	var x, y int
+	if n, err := fmt.Sscanf(reply, "%d,%d", &x, &y); err != nil || n != 2 {
+		return Move{}, fmt.Errorf("%w: expected a move, got %q", ErrProtocol, reply)
+	}
+	if x < 0 || y < 0 || x >= e.cfg.BoardSize || y >= e.cfg.BoardSize {
+		return Move{}, fmt.Errorf("%w: move %q is outside the board", ErrProtocol, reply)
+	}
+	return Move{Row: y, Col: x}, nil
+}
+
+// send 向引擎写入命令行
+func (e *Engine) send(cmds ...string) error {
+	if !e.Alive() {
+		return ErrExited
+	}
+	if _, err := io.WriteString(e.stdin, strings.Join(cmds, "\n")+"\n"); err != nil {
+		return fmt.Errorf("%w: %v", ErrExited, err)
+	}
+	return nil
+}
+
+// readReply 读取下一条回复，ERROR 和 UNKNOWN 作为错误返回
+func (e *Engine) readReply(timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case line, ok := <-e.lines:
+		if !ok {
+			return "", ErrExited
+		}
+		if strings.HasPrefix(line, "ERROR") || strings.HasPrefix(line, "UNKNOWN") {
+			return "", fmt.Errorf("%w: %s", ErrProtocol, line)
+		}
+		return line, nil
+	case <-timer.C:
+		return "", fmt.Errorf("%w after %v", ErrTimeout, timeout)
+	}
+}
+
+// drain 丢弃上一条命令之后多余的输出，避免把它当作本次的回复
+func (e *Engine) drain() {
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return
+			}
+			log.Printf("[%s] ignoring stale output: %s", e.cfg.Command, line)
+		default:
+			return
+		}
+	}
+}
+
+// readLoop 逐行读取引擎输出，信息行只记录日志，其余转发给 readReply；输出结束后回收进程
+func (e *Engine) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if word, _, _ := strings.Cut(line, " "); word == "MESSAGE" || word == "DEBUG" || word == "SUGGEST" {
+			log.Printf("[%s] %s", e.cfg.Command, line)
+			continue
+		}
+		select {
+		case e.lines <- line:
+		default:
+			log.Printf("[%s] dropping unexpected output: %s", e.cfg.Command, line)
+		}
+	}
+	close(e.lines)
+
+	if err := e.cmd.Wait(); err != nil {
+		log.Printf("Engine %s exited: %v", e.cfg.Command, err)
+	}
+	close(e.exited)
+}
+
+// kill 强制终止进程，回收由 readLoop 完成
+func (e *Engine) kill() {
+	if e.cmd.Process != nil {
+		killProcess(e.cmd)
+	}
+}
+
+// logWriter 把引擎的标准错误输出写入日志
+type logWriter struct {
+	prefix string
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		log.Printf("[%s] %s", w.prefix, line)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,183 @@
+package piskvork
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+const fakeEngine = "./testdata/fake-engine.sh"
+
+// startFake 以指定模式启动 testdata 中的假引擎，测试结束时关闭
+func startFake(t *testing.T, mode string) (*Engine, error) {
+	t.Helper()
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("fake engine needs bash")
+	}
+	t.Setenv("FAKE_ENGINE_MODE", mode)
+	t.Setenv("FAKE_ENGINE_DELAY", "5")
+
+	e, err := Start(Config{
+		Command:     fakeEngine,
+		BoardSize:   15,
+		TurnTimeout: 200 * time.Millisecond,
+		Grace:       100 * time.Millisecond,
+	})
+	if e != nil {
+		t.Cleanup(e.Close)
+	}
+	return e, err
+}
+
+// waitExited 等待进程退出，超过 2 秒仍在运行时测试失败
+func waitExited(t *testing.T, e *Engine) {
+	t.Helper()
+	select {
+	case <-e.exited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("engine is still running")
+	}
+}
+
+// position 一个有双方棋子的局面，引擎执白
+var position = []Stone{
+	{Move: Move{Row: 7, Col: 7}},
+	{Move: Move{Row: 7, Col: 8}, Own: true},
+	{Move: Move{Row: 8, Col: 8}},
+}
+
+// occupied 判断落子点是否已有棋子
+func occupied(stones []Stone, m Move) bool {
+	for _, s := range stones {
+		if s.Move == m {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRestart 以正常模式重新启动引擎，检查它能在同一局面上给出合法着法
+func checkRestart(t *testing.T) {
+	t.Helper()
+	e, err := startFake(t, "")
+	if err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	m, err := e.Board(position, 0)
+	if err != nil {
+		t.Fatalf("restart: Board: %v", err)
+	}
+	if occupied(position, m) {
+		t.Fatalf("restart: engine played occupied point %v", m)
+	}
+}
+
+func TestEngineMoves(t *testing.T) {
+	e, err := startFake(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := e.Begin(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != (Move{Row: 7, Col: 7}) {
+		t.Errorf("Begin = %v, want the center", m)
+	}
+
+	// TURN 的坐标是 "列,行"，引擎应记住对方落在 (6, 7)
+	m, err = e.Turn(Move{Row: 6, Col: 7}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m == (Move{Row: 7, Col: 7}) || m == (Move{Row: 6, Col: 7}) {
+		t.Errorf("Turn = %v, an occupied point", m)
+	}
+
+	m, err = e.Board(position, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if occupied(position, m) {
+		t.Errorf("Board = %v, an occupied point", m)
+	}
+
+	e.Close()
+	if e.Alive() {
+		t.Error("engine is still running after Close")
+	}
+}
+
+func TestEngineFailures(t *testing.T) {
+	tests := []struct {
+		mode  string
+		want  error
+		alive bool // 出错后进程是否仍在运行
+	}{
+		{mode: "slow", want: ErrTimeout},
+		{mode: "crash", want: ErrExited},
+		{mode: "garbage", want: ErrProtocol, alive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			e, err := startFake(t, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			if _, err := e.Board(position, 0); !errors.Is(err, tt.want) {
+				t.Fatalf("Board: err = %v, want %v", err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Board took %v", elapsed)
+			}
+			if tt.alive {
+				if !e.Alive() {
+					t.Error("engine exited")
+				}
+			} else {
+				// 超时后进程被终止，崩溃的进程已经退出，之后的请求都应立即失败
+				waitExited(t, e)
+				if _, err := e.Board(position, 0); !errors.Is(err, ErrExited) {
+					t.Errorf("Board after exit: err = %v, want ErrExited", err)
+				}
+			}
+
+			checkRestart(t)
+		})
+	}
+}
+
+func TestEngineOccupiedReply(t *testing.T) {
+	e, err := startFake(t, "occupied")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 引擎不检查落子点是否有棋子，由调用方拒绝
+	m, err := e.Board(position, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !occupied(position, m) {
+		t.Fatalf("Board = %v, want an occupied point", m)
+	}
+
+	checkRestart(t)
+}
+
+func TestEngineStartError(t *testing.T) {
+	e, err := startFake(t, "error")
+	if !errors.Is(err, ErrProtocol) {
+		t.Fatalf("Start: err = %v, want ErrProtocol", err)
+	}
+	if e != nil {
+		t.Fatal("Start returned an engine on error")
+	}
+
+	checkRestart(t)
+}
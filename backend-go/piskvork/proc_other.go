@@ -0,0 +1,13 @@
+//go:build !unix
+
+package piskvork
+
+import "os/exec"
+
+// setProcessGroup 非 Unix 平台不设置进程组
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcess 终止引擎进程
+func killProcess(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
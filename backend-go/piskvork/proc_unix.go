@@ -0,0 +1,18 @@
+//go:build unix
+
+package piskvork
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让引擎在独立的进程组中运行，终止时连同它启动的子进程一起结束
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcess 终止引擎所在的整个进程组
+func killProcess(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,86 @@
+#!/usr/bin/env bash
+# 测试用的假 Piskvork 引擎：不做任何计算，总是下离中心最近的空点。
+#
+# 用法（本地测试外部引擎对局）:
+#
+#   PISKVORK_ENGINES=fake=./piskvork/testdata/fake-engine.sh go run .
+#
+# FAKE_ENGINE_MODE 模拟异常的引擎：
+#   slow      每步先等待 FAKE_ENGINE_DELAY 秒（默认 30）再回复，用于测试每步超时
+#   crash     收到第一个落子请求时退出，用于测试进程重启
+#   garbage   回复无法解析的内容
+#   occupied  回复一个已有棋子的点
+#   error     START 时回复 ERROR
+
+size=0
+declare -A board
+
+reset() {
+	board=()
+}
+
+# 回复离中心最近的空点，x 为列，y 为行
+play() {
+	case "$FAKE_ENGINE_MODE" in
+	slow) sleep "${FAKE_ENGINE_DELAY:-30}" ;;
+	crash) exit 1 ;;
+	garbage) echo "hello"; return ;;
+	occupied)
+		for key in "${!board[@]}"; do
+			echo "$key"
+			return
+		done
+		;;
+	esac
+
+	local center=$((size / 2)) r x y
+	for ((r = 0; r < size; r++)); do
+		for ((y = center - r; y <= center + r; y++)); do
+			for ((x = center - r; x <= center + r; x++)); do
+				((x < 0 || y < 0 || x >= size || y >= size)) && continue
+				[[ -n "${board[$x,$y]}" ]] && continue
+				board[$x,$y]=1
+				echo "MESSAGE fake engine plays $x,$y"
+				echo "$x,$y"
+				return
+			done
+		done
+	done
+	echo "ERROR board is full"
+}
+
+while IFS= read -r line; do
+	line=${line%$'\r'}
+	case "$line" in
+	"START "*)
+		size=${line#START }
+		if [[ "$FAKE_ENGINE_MODE" == error ]]; then
+			echo "ERROR unsupported"
+		else
+			reset
+			echo "OK"
+		fi
+		;;
+	"INFO "*) ;;
+	BEGIN)
+		reset
+		play
+		;;
+	"TURN "*)
+		board[${line#TURN }]=2
+		play
+		;;
+	BOARD)
+		reset
+		while IFS= read -r stone; do
+			stone=${stone%$'\r'}
+			[[ "$stone" == DONE ]] && break
+			board[${stone%,*}]=${stone##*,}
+		done
+		play
+		;;
+	ABOUT) echo 'name="fake", version="1.0", author="gomoku-backend"' ;;
+	END) exit 0 ;;
+	*) echo "UNKNOWN $line" ;;
+	esac
+done
@@ -0,0 +1,30 @@
+package realtime
+
+import (
+	"context"
+
+	"gomoku-backend/config"
+)
+
+// AzureBroadcaster 基于 Azure Web PubSub REST API 的推送服务
+type AzureBroadcaster struct{}
+
+// GetClientAccessToken 获取 Web PubSub 客户端访问令牌
+func (AzureBroadcaster) GetClientAccessToken(ctx context.Context, userID string, roomID string) (*config.ClientTokenResponse, error) {
+	return config.GetClientAccessToken(ctx, userID, roomID)
+}
+
+// SendToRoom 向房间发送消息
+func (AzureBroadcaster) SendToRoom(ctx context.Context, roomID string, message interface{}) error {
+	return config.SendToRoom(ctx, roomID, message)
+}
+
+// AddUserToRoom 将用户添加到房间组
+func (AzureBroadcaster) AddUserToRoom(ctx context.Context, userID string, roomID string) error {
+	return config.AddUserToRoom(ctx, userID, roomID)
+}
+
+// RemoveUserFromRoom 从房间组移除用户
+func (AzureBroadcaster) RemoveUserFromRoom(ctx context.Context, userID string, roomID string) error {
+	return config.RemoveUserFromRoom(ctx, userID, roomID)
+}
@@ -0,0 +1,37 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gomoku-backend/config"
+)
+
+// Broadcaster 实时消息推送接口
+type Broadcaster interface {
+	// GetClientAccessToken 获取客户端建立 WebSocket 连接所需的地址和令牌
+	GetClientAccessToken(ctx context.Context, userID string, roomID string) (*config.ClientTokenResponse, error)
+	// SendToRoom 向房间组内所有连接推送消息
+	SendToRoom(ctx context.Context, roomID string, message interface{}) error
+	// AddUserToRoom 将用户加入房间组
+	AddUserToRoom(ctx context.Context, userID string, roomID string) error
+	// RemoveUserFromRoom 将用户移出房间组
+	RemoveUserFromRoom(ctx context.Context, userID string, roomID string) error
+}
+
+// New 根据 REALTIME_DRIVER 环境变量创建推送服务（azure 或 websocket，默认 azure）
+func New() (Broadcaster, error) {
+	driver := os.Getenv("REALTIME_DRIVER")
+	switch driver {
+	case "", "azure":
+		if err := config.InitPubSub(); err != nil {
+			return nil, err
+		}
+		return AzureBroadcaster{}, nil
+	case "websocket":
+		return NewHub(os.Getenv("WS_PUBLIC_URL"), os.Getenv("WS_TOKEN_SECRET")), nil
+	default:
+		return nil, fmt.Errorf("unknown REALTIME_DRIVER: %s", driver)
+	}
+}
@@ -0,0 +1,373 @@
+package realtime
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gomoku-backend/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// sendQueueSize 每个连接的发送队列长度，队列满时断开该连接
+	sendQueueSize = 64
+	// writeWait 单条消息的写超时
+	writeWait = 10 * time.Second
+	// pongWait 等待客户端 pong 的超时
+	pongWait = 60 * time.Second
+	// pingPeriod 发送 ping 的间隔，必须小于 pongWait
+	pingPeriod = pongWait * 9 / 10
+	// maxMessageSize 客户端消息的最大长度
+	maxMessageSize = 4096
+	// tokenTTL 连接令牌有效期
+	tokenTTL = time.Hour
+)
+
+// Hub 内置 WebSocket 推送服务，由 Gin 路由器在 /ws 提供，
+// 用于本地开发和自托管部署，消息格式与 Azure Web PubSub 一致
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[string]map[*wsClient]struct{} // userID -> 连接
+	groups  map[string]map[string]struct{}    // roomID -> userID
+
+	secret    []byte
+	publicURL string
+	upgrader  websocket.Upgrader
+
+	// OnDisconnect 用户的最后一个连接断开时调用
+	OnDisconnect func(userID string)
+}
+
+// wsClient 单个 WebSocket 连接
+type wsClient struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	userID string
+	send   chan []byte
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// hubToken 连接令牌内容
+type hubToken struct {
+	Sub   string `json:"sub"`
+	Group string `json:"group,omitempty"`
+	Exp   int64  `json:"exp"`
+}
+
+// NewHub 创建 WebSocket 推送服务
+//
+// publicURL 为客户端可访问的 /ws 地址，为空时使用 ws://localhost:$PORT/ws；
+// secret 为令牌签名密钥，为空时启动时随机生成（重启后旧令牌失效）。
+func NewHub(publicURL string, secret string) *Hub {
+	if publicURL == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "3000"
+		}
+		publicURL = fmt.Sprintf("ws://localhost:%s/ws", port)
+	}
+
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("Failed to generate WebSocket token secret: %v", err)
+		}
+	}
+
+	return &Hub{
+		clients:   make(map[string]map[*wsClient]struct{}),
+		groups:    make(map[string]map[string]struct{}),
+		secret:    key,
+		publicURL: publicURL,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			// 小程序和本地调试页面的 Origin 各不相同，鉴权依赖令牌
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// GetClientAccessToken 签发连接令牌
+func (h *Hub) GetClientAccessToken(ctx context.Context, userID string, roomID string) (*config.ClientTokenResponse, error) {
+	payload, err := json.Marshal(hubToken{
+		Sub:   userID,
+		Group: roomID,
+		Exp:   time.Now().Add(tokenTTL).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	message := base64.RawURLEncoding.EncodeToString(payload)
+	token := message + "." + h.sign(message)
+
+	return &config.ClientTokenResponse{
+		Token: token,
+		URL:   h.publicURL + "?access_token=" + url.QueryEscape(token),
+	}, nil
+}
+
+// SendToRoom 向房间组内所有连接推送消息
+func (h *Hub) SendToRoom(ctx context.Context, roomID string, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	h.mu.RLock()
+	var targets []*wsClient
+	for userID := range h.groups[roomID] {
+		for c := range h.clients[userID] {
+			targets = append(targets, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range targets {
+		c.enqueue(data)
+	}
+	return nil
+}
+
+// AddUserToRoom 将用户加入房间组
+func (h *Hub) AddUserToRoom(ctx context.Context, userID string, roomID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	members, ok := h.groups[roomID]
+	if !ok {
+		members = make(map[string]struct{})
+		h.groups[roomID] = members
+	}
+	members[userID] = struct{}{}
+	return nil
+}
+
+// RemoveUserFromRoom 将用户移出房间组
+func (h *Hub) RemoveUserFromRoom(ctx context.Context, userID string, roomID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeFromGroup(userID, roomID)
+	return nil
+}
+
+// ServeWS 处理 WebSocket 升级请求，令牌通过 access_token 查询参数传递
+func (h *Hub) ServeWS(c *gin.Context) {
+	token, err := h.verify(c.Query("access_token"))
+	if err != nil {
+		c.JSON(401, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("[WebSocket] Upgrade failed: %v", err)
+		return
+	}
+
+	client := &wsClient{
+		hub:    h,
+		conn:   conn,
+		userID: token.Sub,
+		send:   make(chan []byte, sendQueueSize),
+	}
+
+	h.mu.Lock()
+	conns, ok := h.clients[client.userID]
+	if !ok {
+		conns = make(map[*wsClient]struct{})
+		h.clients[client.userID] = conns
+	}
+	conns[client] = struct{}{}
+	h.mu.Unlock()
+
+	if token.Group != "" {
+		_ = h.AddUserToRoom(c.Request.Context(), client.userID, token.Group)
+	}
+
+	log.Printf("[WebSocket] User connected: %s", client.userID)
+
+	go client.writePump()
+	go client.readPump()
+}
+
+// unregister 移除连接，用户没有其他连接时退出所有房间组并触发 OnDisconnect
+func (h *Hub) unregister(c *wsClient) {
+	h.mu.Lock()
+	conns := h.clients[c.userID]
+	delete(conns, c)
+	lastConn := len(conns) == 0
+	if lastConn {
+		delete(h.clients, c.userID)
+		for roomID := range h.groups {
+			h.removeFromGroup(c.userID, roomID)
+		}
+	}
+	h.mu.Unlock()
+
+	log.Printf("[WebSocket] User disconnected: %s", c.userID)
+
+	if lastConn && h.OnDisconnect != nil {
+		h.OnDisconnect(c.userID)
+	}
+}
+
+// removeFromGroup 将用户移出房间组，调用方需持有写锁
+func (h *Hub) removeFromGroup(userID string, roomID string) {
+	members, ok := h.groups[roomID]
+	if !ok {
+		return
+	}
+	delete(members, userID)
+	if len(members) == 0 {
+		delete(h.groups, roomID)
+	}
+}
+
+// sign 计算令牌签名
+func (h *Hub) sign(message string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(message))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify 校验令牌签名和有效期
+func (h *Hub) verify(token string) (*hubToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(h.sign(parts[0]))) {
+		return nil, fmt.Errorf("invalid access token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token")
+	}
+
+	var t hubToken
+	if err := json.Unmarshal(payload, &t); err != nil || t.Sub == "" {
+		return nil, fmt.Errorf("invalid access token")
+	}
+	if time.Now().Unix() > t.Exp {
+		return nil, fmt.Errorf("access token expired")
+	}
+	return &t, nil
+}
+
+// enqueue 将消息放入发送队列，队列已满说明客户端消费过慢，直接断开
+func (c *wsClient) enqueue(data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		log.Printf("[WebSocket] Send queue full, dropping connection of %s", c.userID)
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// close 关闭发送队列，writePump 随后发送关闭帧并断开连接
+func (c *wsClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// readPump 读取客户端消息，处理 joinGroup/leaveGroup
+func (c *wsClient) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.close()
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var message struct {
+			Type  string `json:"type"`
+			Group string `json:"group"`
+		}
+		if err := json.Unmarshal(data, &message); err != nil {
+			log.Printf("[WebSocket] Invalid message from %s: %v", c.userID, err)
+			continue
+		}
+
+		ctx := context.Background()
+		switch message.Type {
+		case "joinGroup":
+			if message.Group != "" {
+				_ = c.hub.AddUserToRoom(ctx, c.userID, message.Group)
+				log.Printf("[WebSocket] Added user %s to group %s", c.userID, message.Group)
+			}
+		case "leaveGroup":
+			if message.Group != "" {
+				_ = c.hub.RemoveUserFromRoom(ctx, c.userID, message.Group)
+			}
+		}
+	}
+}
+
+// writePump 将发送队列中的消息写入连接，并定期发送 ping
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+
+	"gomoku-backend/rules"
+	"gomoku-backend/types"
+)
+
+// GIF 把对局绘制为逐步落子的动画，delay 和 finalDelay 的单位为 1/100 秒
+//
+// 第一帧为空棋盘，之后每步一帧，最后一帧停留 finalDelay；有获胜连子时最后一帧用红圈标出。
+// 除第一帧外每帧只包含有变化的格子（新落的棋子和上一步的标记），并保留上一帧的内容。
+func GIF(w io.Writer, opts Options, delay, finalDelay int) error {
+	l := opts.layout()
+	empty := l.emptyBoard()
+	canvas := image.NewRGBA(empty.Bounds())
+	draw.Draw(canvas, canvas.Bounds(), empty, image.Point{}, draw.Src)
+
+	pal := gifPalette()
+	anim := &gif.GIF{}
+	addFrame := func(r image.Rectangle, delay int) {
+		frame := image.NewPaletted(r, pal)
+		draw.Draw(frame, r, canvas, r.Min, draw.Src)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delay)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	// redraw 在空棋盘的格子上重画棋子
+	redraw := func(m types.Move, marks stoneMarks) image.Rectangle {
+		r := l.cellRect(m.Row, m.Col)
+		draw.Draw(canvas, r, empty, r.Min, draw.Src)
+		l.drawStone(canvas, m, marks)
+		return r
+	}
+
+	addFrame(canvas.Bounds(), delay)
+	for i, m := range opts.Moves {
+		r := redraw(m, stoneMarks{number: i + 1, label: opts.MoveNumbers, last: true})
+		if i > 0 {
+			// 上一步不再是最后一步
+			r = r.Union(redraw(opts.Moves[i-1], stoneMarks{number: i, label: opts.MoveNumbers}))
+		}
+		addFrame(r, delay)
+	}
+
+	// 最后一帧标出获胜的连子
+	win := winPoints(opts.WinLine)
+	if len(win) > 0 {
+		var r image.Rectangle
+		for i, m := range opts.Moves {
+			if win[rules.Point{Row: m.Row, Col: m.Col}] {
+				r = r.Union(redraw(m, stoneMarks{number: i + 1, label: opts.MoveNumbers, last: i == len(opts.Moves)-1, win: true}))
+			}
+		}
+		addFrame(r, finalDelay)
+	} else {
+		anim.Delay[len(anim.Delay)-1] = finalDelay
+	}
+
+	return gif.EncodeAll(w, anim)
+}
+
+// gifPalette 动画的调色板：绘制用的颜色及每两种颜色之间的过渡色，覆盖抗锯齿边缘的混合结果
+func gifPalette() color.Palette {
+	const steps = 8
+	base := []color.RGBA{boardColor, lineColor, blackColor, whiteColor, outlineColor, markColor}
+	pal := color.Palette{}
+	for _, c := range base {
+		pal = append(pal, c)
+	}
+	for i, a := range base {
+		for _, b := range base[i+1:] {
+			for s := 1; s < steps; s++ {
+				pal = append(pal, color.RGBA{
+					R: uint8((int(a.R)*(steps-s) + int(b.R)*s) / steps),
+					G: uint8((int(a.G)*(steps-s) + int(b.G)*s) / steps),
+					B: uint8((int(a.B)*(steps-s) + int(b.B)*s) / steps),
+					A: 0xff,
+				})
+			}
+		}
+	}
+	return pal
+}
@@ -0,0 +1,282 @@
+// Package render 绘制棋盘图片（PNG 和 SVG），只使用标准库，不依赖系统字体和 cgo。
+//
+// 坐标约定与房间一致：Row 0 为棋盘最上方一行，Col 0 为最左一列。
+// 图片下方标注列（A、B、C…），左侧标注行（从下到上为 1、2、3…），与连珠记谱一致。
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+
+	"gomoku-backend/rules"
+	"gomoku-backend/types"
+)
+
+// 格子边长的范围（像素）
+const (
+	MinCellSize     = 16
+	MaxCellSize     = 64
+	DefaultCellSize = 32
+)
+
+// 绘制用的颜色
+var (
+	boardColor   = color.RGBA{0xdc, 0xb3, 0x5c, 0xff}
+	lineColor    = color.RGBA{0x3c, 0x2a, 0x10, 0xff}
+	blackColor   = color.RGBA{0x1a, 0x1a, 0x1a, 0xff}
+	whiteColor   = color.RGBA{0xf8, 0xf8, 0xf8, 0xff}
+	outlineColor = color.RGBA{0x55, 0x55, 0x55, 0xff}
+	markColor    = color.RGBA{0xe5, 0x39, 0x35, 0xff}
+)
+
+// Options 棋盘图片的内容
+type Options struct {
+	BoardSize   int
+	Moves       []types.Move  // 已下的着法，按顺序编号
+	WinLine     []rules.Point // 获胜的连子，为空时不标出
+	CellSize    int           // 格子边长（像素），为 0 时使用默认值
+	MoveNumbers bool          // 在棋子上标出着法序号，否则只标出最后一步
+}
+
+// layout 图片的几何尺寸
+type layout struct {
+	cell   int
+	margin int // 棋盘最外侧的线到图片边缘的距离，放置坐标
+	line   int // 棋盘线宽度（像素）
+	size   int // 棋盘边长（路数）
+}
+
+func (o *Options) layout() layout {
+	cell := o.CellSize
+	if cell == 0 {
+		cell = DefaultCellSize
+	}
+	return layout{cell: cell, margin: cell, line: max(1, cell/32), size: o.BoardSize}
+}
+
+// width 图片边长（像素）
+func (l layout) width() int {
+	return (l.size-1)*l.cell + 2*l.margin
+}
+
+// point 交叉点 (row, col) 中心的像素坐标
+func (l layout) point(row, col int) (x, y float64) {
+	half := float64(l.line) / 2
+	return float64(l.margin+col*l.cell) + half, float64(l.margin+row*l.cell) + half
+}
+
+// starPoints 星位：天元及距边 3 路（小棋盘 2 路）的四个点
+func (l layout) starPoints() []rules.Point {
+	if l.size < 9 {
+		return nil
+	}
+	edge := 3
+	if l.size < 13 {
+		edge = 2
+	}
+	far := l.size - 1 - edge
+	center := l.size / 2
+	return []rules.Point{{Row: edge, Col: edge}, {Row: edge, Col: far}, {Row: far, Col: edge}, {Row: far, Col: far}, {Row: center, Col: center}}
+}
+
+// columnLabel 第 col 列的标注
+func columnLabel(col int) string {
+	return string(rune('A' + col))
+}
+
+// rowLabel 第 row 行的标注，从下到上为 1、2、3…
+func (l layout) rowLabel(row int) string {
+	return strconv.Itoa(l.size - row)
+}
+
+// PNG 把棋盘绘制为 PNG
+func PNG(w io.Writer, opts Options) error {
+	return png.Encode(w, Draw(opts))
+}
+
+// Draw 把棋盘绘制为 RGBA 图片
+func Draw(opts Options) *image.RGBA {
+	l := opts.layout()
+	img := l.emptyBoard()
+	win := winPoints(opts.WinLine)
+	for i, m := range opts.Moves {
+		l.drawStone(img, m, stoneMarks{
+			number: i + 1,
+			label:  opts.MoveNumbers,
+			last:   i == len(opts.Moves)-1,
+			win:    win[rules.Point{Row: m.Row, Col: m.Col}],
+		})
+	}
+	return img
+}
+
+// stoneMarks 棋子上的标记
+type stoneMarks struct {
+	number int  // 着法序号
+	label  bool // 是否显示序号
+	last   bool // 最后一步：序号为红色，不显示序号时画红点
+	win    bool // 获胜连子中的棋子：画红圈
+}
+
+// emptyBoard 绘制只有棋盘线、星位和坐标的空棋盘
+func (l layout) emptyBoard() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, l.width(), l.width()))
+	draw.Draw(img, img.Bounds(), image.NewUniform(boardColor), image.Point{}, draw.Src)
+
+	c := float64(l.cell)
+
+	// 棋盘线和星位
+	first, last := l.margin, l.margin+(l.size-1)*l.cell
+	for i := 0; i < l.size; i++ {
+		p := l.margin + i*l.cell
+		fillRect(img, image.Rect(first, p, last+l.line, p+l.line), lineColor)
+		fillRect(img, image.Rect(p, first, p+l.line, last+l.line), lineColor)
+	}
+	for _, p := range l.starPoints() {
+		x, y := l.point(p.Row, p.Col)
+		fillCircle(img, x, y, c/10, lineColor)
+	}
+
+	// 坐标
+	for i := 0; i < l.size; i++ {
+		x, _ := l.point(0, i)
+		drawText(img, columnLabel(i), x, float64(last)+c*0.6, l.textScale(), lineColor)
+		_, y := l.point(i, 0)
+		drawText(img, l.rowLabel(i), float64(first)-c*0.6, y, l.textScale(), lineColor)
+	}
+	return img
+}
+
+// drawStone 绘制一颗棋子及其标记，只改动该点所在格子（cellRect）内的像素
+func (l layout) drawStone(img *image.RGBA, m types.Move, marks stoneMarks) {
+	c := float64(l.cell)
+	x, y := l.point(m.Row, m.Col)
+	radius := l.radius()
+	if m.Player == rules.Black {
+		fillCircle(img, x, y, radius, blackColor)
+	} else {
+		fillCircle(img, x, y, radius, outlineColor)
+		fillCircle(img, x, y, radius-math.Max(1, c/24), whiteColor)
+	}
+
+	if marks.win {
+		fillRing(img, x, y, radius-winRingWidth(c), radius, markColor)
+	}
+
+	switch {
+	case marks.label:
+		textColor := stoneTextColor(m.Player)
+		if marks.last {
+			textColor = markColor
+		}
+		label := strconv.Itoa(marks.number)
+		drawText(img, label, x, y, numberScale(label, radius, l.textScale()), textColor)
+	case marks.last:
+		fillCircle(img, x, y, c/8, markColor)
+	}
+}
+
+// cellRect 交叉点 (row, col) 所在格子的像素范围，包含该点的棋子
+func (l layout) cellRect(row, col int) image.Rectangle {
+	x, y := l.point(row, col)
+	half := float64(l.cell) / 2
+	return image.Rect(int(math.Floor(x-half)), int(math.Floor(y-half)), int(math.Ceil(x+half)), int(math.Ceil(y+half)))
+}
+
+// radius 棋子半径
+func (l layout) radius() float64 {
+	return float64(l.cell) * 0.46
+}
+
+// textScale 坐标和着法序号点阵字体的放大倍数
+func (l layout) textScale() int {
+	return max(1, l.cell/16)
+}
+
+// winPoints 获胜连子的坐标集合
+func winPoints(line []rules.Point) map[rules.Point]bool {
+	win := make(map[rules.Point]bool, len(line))
+	for _, p := range line {
+		win[p] = true
+	}
+	return win
+}
+
+// stoneTextColor 棋子上文字的颜色
+func stoneTextColor(player int) color.RGBA {
+	if player == rules.Black {
+		return whiteColor
+	}
+	return blackColor
+}
+
+// winRingWidth 获胜连子红圈的宽度
+func winRingWidth(cell float64) float64 {
+	return math.Max(2, cell/10)
+}
+
+// numberScale 着法序号的放大倍数，保证序号不超出棋子
+func numberScale(label string, radius float64, scale int) int {
+	for scale > 1 && float64(textWidth(label, scale)) > radius*1.6 {
+		scale--
+	}
+	return scale
+}
+
+// fillRect 用纯色填充矩形
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// fillCircle 绘制抗锯齿的实心圆
+func fillCircle(img *image.RGBA, cx, cy, r float64, c color.RGBA) {
+	fillShape(img, cx-r, cy-r, cx+r, cy+r, c, func(x, y float64) bool {
+		return (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r
+	})
+}
+
+// fillRing 绘制内外半径分别为 r0、r1 的抗锯齿圆环
+func fillRing(img *image.RGBA, cx, cy, r0, r1 float64, c color.RGBA) {
+	fillShape(img, cx-r1, cy-r1, cx+r1, cy+r1, c, func(x, y float64) bool {
+		d := (x-cx)*(x-cx) + (y-cy)*(y-cy)
+		return d >= r0*r0 && d <= r1*r1
+	})
+}
+
+// fillShape 在包围盒内按 4x4 超采样计算 inside 的覆盖率，与原有像素混合
+func fillShape(img *image.RGBA, x0, y0, x1, y1 float64, c color.RGBA, inside func(x, y float64) bool) {
+	const samples = 4
+	bounds := image.Rect(int(math.Floor(x0)), int(math.Floor(y0)), int(math.Ceil(x1))+1, int(math.Ceil(y1))+1).Intersect(img.Bounds())
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			covered := 0
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					if inside(float64(px)+(float64(sx)+0.5)/samples, float64(py)+(float64(sy)+0.5)/samples) {
+						covered++
+					}
+				}
+			}
+			if covered > 0 {
+				blend(img, px, py, c, covered*255/(samples*samples))
+			}
+		}
+	}
+}
+
+// blend 以 alpha（0-255）把颜色 c 叠加到像素 (x, y) 上
+func blend(img *image.RGBA, x, y int, c color.RGBA, alpha int) {
+	i := img.PixOffset(x, y)
+	pix := img.Pix[i : i+3 : i+3]
+	mix := func(dst, src uint8) uint8 {
+		return uint8((int(dst)*(255-alpha) + int(src)*alpha) / 255)
+	}
+	pix[0] = mix(pix[0], c.R)
+	pix[1] = mix(pix[1], c.G)
+	pix[2] = mix(pix[2], c.B)
+}
@@ -0,0 +1,115 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+
+	"gomoku-backend/rules"
+)
+
+// SVG 把棋盘绘制为 SVG，布局与 PNG 相同，文字使用查看端的无衬线字体
+func SVG(w io.Writer, opts Options) error {
+	l := opts.layout()
+	c := float64(l.cell)
+	width := l.width()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, width, width, width)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`+"\n", width, width, hex(boardColor))
+
+	// 棋盘线和星位
+	first, _ := l.point(0, 0)
+	last, _ := l.point(0, l.size-1)
+	fmt.Fprintf(&buf, `<g stroke="%s" stroke-width="%d">`+"\n", hex(lineColor), l.line)
+	for i := 0; i < l.size; i++ {
+		_, p := l.point(i, 0)
+		fmt.Fprintf(&buf, `<line x1="%s" y1="%s" x2="%s" y2="%s"/>`+"\n", num(first), num(p), num(last), num(p))
+		fmt.Fprintf(&buf, `<line x1="%s" y1="%s" x2="%s" y2="%s"/>`+"\n", num(p), num(first), num(p), num(last))
+	}
+	buf.WriteString("</g>\n")
+	for _, p := range l.starPoints() {
+		x, y := l.point(p.Row, p.Col)
+		circle(&buf, x, y, c/10, lineColor, "")
+	}
+
+	// 坐标
+	fontSize := c * 0.4
+	fmt.Fprintf(&buf, `<g font-family="sans-serif" font-size="%s" fill="%s" text-anchor="middle" dominant-baseline="central">`+"\n", num(fontSize), hex(lineColor))
+	for i := 0; i < l.size; i++ {
+		x, _ := l.point(0, i)
+		text(&buf, columnLabel(i), x, last+c*0.6, nil)
+		_, y := l.point(i, 0)
+		text(&buf, l.rowLabel(i), first-c*0.6, y, nil)
+	}
+	buf.WriteString("</g>\n")
+
+	// 棋子
+	radius := l.radius()
+	for _, m := range opts.Moves {
+		x, y := l.point(m.Row, m.Col)
+		if m.Player == rules.Black {
+			circle(&buf, x, y, radius, blackColor, "")
+		} else {
+			circle(&buf, x, y, radius, whiteColor, fmt.Sprintf(` stroke="%s" stroke-width="%s"`, hex(outlineColor), num(max(1, c/24))))
+		}
+	}
+
+	// 获胜的连子用红圈标出
+	ring := winRingWidth(c)
+	for _, p := range opts.WinLine {
+		x, y := l.point(p.Row, p.Col)
+		fmt.Fprintf(&buf, `<circle cx="%s" cy="%s" r="%s" fill="none" stroke="%s" stroke-width="%s"/>`+"\n",
+			num(x), num(y), num(radius-ring/2), hex(markColor), num(ring))
+	}
+
+	// 着法序号和最后一步标记
+	fmt.Fprintf(&buf, `<g font-family="sans-serif" font-size="%s" text-anchor="middle" dominant-baseline="central">`+"\n", num(fontSize))
+	for i, m := range opts.Moves {
+		isLast := i == len(opts.Moves)-1
+		if !opts.MoveNumbers && !isLast {
+			continue
+		}
+		x, y := l.point(m.Row, m.Col)
+		if !opts.MoveNumbers {
+			circle(&buf, x, y, c/8, markColor, "")
+			continue
+		}
+		textColor := stoneTextColor(m.Player)
+		if isLast {
+			textColor = markColor
+		}
+		text(&buf, strconv.Itoa(i+1), x, y, &textColor)
+	}
+	buf.WriteString("</g>\n</svg>\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// circle 输出实心圆，extra 为附加的属性
+func circle(buf *bytes.Buffer, x, y, r float64, fill color.RGBA, extra string) {
+	fmt.Fprintf(buf, `<circle cx="%s" cy="%s" r="%s" fill="%s"%s/>`+"\n", num(x), num(y), num(r), hex(fill), extra)
+}
+
+// text 输出文字，fill 为空时继承外层的颜色
+func text(buf *bytes.Buffer, s string, x, y float64, fill *color.RGBA) {
+	if fill == nil {
+		fmt.Fprintf(buf, `<text x="%s" y="%s">%s</text>`+"\n", num(x), num(y), s)
+		return
+	}
+	fmt.Fprintf(buf, `<text x="%s" y="%s" fill="%s">%s</text>`+"\n", num(x), num(y), hex(*fill), s)
+}
+
+// num 格式化坐标，最多保留两位小数
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// hex 颜色的 #rrggbb 表示
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
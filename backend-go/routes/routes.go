@@ -2,17 +2,32 @@ package routes
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"time"
 
-	"gomoku-backend/config"
+	"gomoku-backend/realtime"
 	"gomoku-backend/services"
+	"gomoku-backend/store"
 	"gomoku-backend/types"
 
 	"github.com/gin-gonic/gin"
 )
 
+// broadcaster 实时消息推送
+var broadcaster realtime.Broadcaster
+
 // RegisterRoutes 注册所有路由
-func RegisterRoutes(router *gin.Engine) {
+func RegisterRoutes(router *gin.Engine, b realtime.Broadcaster) {
+	broadcaster = b
+
+	// 内置 WebSocket 推送服务
+	if hub, ok := b.(*realtime.Hub); ok {
+		hub.OnDisconnect = handleUserDisconnected
+		router.GET("/ws", hub.ServeWS)
+	}
+
 	api := router.Group("/api")
 
 	// 获取 PubSub 连接令牌
@@ -20,11 +35,42 @@ func RegisterRoutes(router *gin.Engine) {
 
 	// 房间相关路由
 	api.POST("/rooms/create", createRoom)
+	api.POST("/rooms/import", importGame)
+	api.POST("/rooms/replay", replayGame)
+	api.POST("/rooms/review/step", stepReview)
 	api.GET("/rooms", getRooms)
 	api.GET("/rooms/:roomId", getRoom)
 	api.POST("/rooms/join", joinRoom)
 	api.POST("/rooms/move", makeMove)
 	api.POST("/rooms/leave", leaveRoom)
+	api.POST("/rooms/resign", resign)
+	api.POST("/rooms/draw/offer", offerDraw)
+	api.POST("/rooms/draw/respond", respondDraw)
+	api.POST("/rooms/takeback/request", requestTakeback)
+	api.POST("/rooms/takeback/respond", respondTakeback)
+	api.POST("/rooms/rematch/offer", offerRematch)
+	api.POST("/rooms/rematch/respond", respondRematch)
+	api.POST("/rooms/opening/choose", chooseOpeningColor)
+	api.GET("/rooms/:roomId/hint", getHint)
+
+	// 可用的外部引擎
+	api.GET("/engines", func(c *gin.Context) {
+		c.JSON(200, gin.H{"engines": services.ExternalEngineNames()})
+	})
+
+	// 局面分析
+	api.POST("/analyze", analyzePosition)
+	api.POST("/solve", solvePosition)
+	api.GET("/games/:id/analysis", getGameAnalysis)
+
+	// 对局记录
+	api.GET("/games/:id", getGame)
+	api.GET("/games/:id/position", getGamePosition)
+	api.GET("/games/:id/image.png", gameImage(services.ImagePNG))
+	api.GET("/games/:id/image.svg", gameImage(services.ImageSVG))
+	api.GET("/games/:id/replay.gif", gameReplay)
+	api.GET("/games/:id/export", exportGame)
+	api.GET("/users/:userId/games", listUserGames)
 
 	// Web PubSub 事件处理
 	api.OPTIONS("/webpubsub/event", handleWebPubSubOptions)
@@ -48,7 +94,7 @@ func getToken(c *gin.Context) {
 	}
 
 	ctx := context.Background()
-	token, err := config.GetClientAccessToken(ctx, req.UserID, req.RoomID)
+	token, err := broadcaster.GetClientAccessToken(ctx, req.UserID, req.RoomID)
 	if err != nil {
 		log.Printf("Error getting token: %v", err)
 		c.JSON(500, gin.H{"error": err.Error()})
@@ -70,7 +116,68 @@ func createRoom(c *gin.Context) {
 	room, err := services.CreateRoom(ctx, req)
 	if err != nil {
 		log.Printf("Error creating room: %v", err)
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// importGame 导入对局记录创建复盘房间
+func importGame(c *gin.Context) {
+	var req types.ImportGameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.ImportGame(ctx, req)
+	if err != nil {
+		log.Printf("Error importing game: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// replayGame 回放归档对局，创建复盘房间
+func replayGame(c *gin.Context) {
+	var req types.ReplayGameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.ReplayGame(ctx, req)
+	if err != nil {
+		log.Printf("Error replaying game: %v", err)
+		if errors.Is(err, store.ErrGameNotFound) {
+			c.JSON(404, gin.H{"error": "Game not found"})
+			return
+		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// stepReview 复盘房间跳转到指定步数
+func stepReview(c *gin.Context) {
+	var req types.ReviewStepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.StepReview(ctx, req)
+	if err != nil {
+		log.Printf("Error stepping review: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -117,7 +224,7 @@ func joinRoom(c *gin.Context) {
 	room, err := services.JoinRoom(ctx, req)
 	if err != nil {
 		log.Printf("Error joining room: %v", err)
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -136,7 +243,7 @@ func makeMove(c *gin.Context) {
 	room, err := services.MakeMove(ctx, req)
 	if err != nil {
 		log.Printf("Error making move: %v", err)
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -155,13 +262,414 @@ func leaveRoom(c *gin.Context) {
 	err := services.LeaveRoom(ctx, req)
 	if err != nil {
 		log.Printf("Error leaving room: %v", err)
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(200, gin.H{"success": true})
 }
 
+// resign 认输
+func resign(c *gin.Context) {
+	var req types.ResignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.Resign(ctx, req)
+	if err != nil {
+		log.Printf("Error resigning: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// offerDraw 提议和棋
+func offerDraw(c *gin.Context) {
+	var req types.DrawOfferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.OfferDraw(ctx, req)
+	if err != nil {
+		log.Printf("Error offering draw: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// respondDraw 回应和棋提议
+func respondDraw(c *gin.Context) {
+	var req types.DrawRespondRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.RespondDraw(ctx, req)
+	if err != nil {
+		log.Printf("Error responding to draw offer: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// requestTakeback 请求悔棋
+func requestTakeback(c *gin.Context) {
+	var req types.TakebackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.RequestTakeback(ctx, req)
+	if err != nil {
+		log.Printf("Error requesting takeback: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// respondTakeback 回应悔棋请求
+func respondTakeback(c *gin.Context) {
+	var req types.TakebackRespondRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.RespondTakeback(ctx, req)
+	if err != nil {
+		log.Printf("Error responding to takeback request: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// offerRematch 提议再来一局
+func offerRematch(c *gin.Context) {
+	var req types.RematchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.OfferRematch(ctx, req)
+	if err != nil {
+		log.Printf("Error offering rematch: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// respondRematch 回应再来一局提议
+func respondRematch(c *gin.Context) {
+	var req types.RematchRespondRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.RespondRematch(ctx, req)
+	if err != nil {
+		log.Printf("Error responding to rematch offer: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// chooseOpeningColor 开局阶段选择颜色
+func chooseOpeningColor(c *gin.Context) {
+	var req types.OpeningChooseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	room, err := services.ChooseOpeningColor(ctx, req)
+	if err != nil {
+		log.Printf("Error choosing opening color: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, room)
+}
+
+// getHint 获取落子提示
+func getHint(c *gin.Context) {
+	roomID := c.Param("roomId")
+	userID := c.Query("userId")
+	if userID == "" {
+		c.JSON(400, gin.H{"error": "userId is required"})
+		return
+	}
+
+	ctx := context.Background()
+	hint, err := services.GetHint(ctx, roomID, userID)
+	if err != nil {
+		log.Printf("Error getting hint: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, hint)
+}
+
+// analyzePosition 分析任意局面
+func analyzePosition(c *gin.Context) {
+	var req types.AnalyzeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	analysis, err := services.AnalyzePosition(ctx, req)
+	if err != nil {
+		log.Printf("Error analyzing position: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, analysis)
+}
+
+// solvePosition 搜索任意局面的 VCF/VCT 必胜序列
+func solvePosition(c *gin.Context) {
+	var req types.SolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	result, err := services.SolvePosition(ctx, req)
+	if err != nil {
+		log.Printf("Error solving position: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, result)
+}
+
+// getGameAnalysis 获取赛后分析报告
+func getGameAnalysis(c *gin.Context) {
+	gameID := c.Param("id")
+
+	ctx := context.Background()
+	analysis, err := services.GetGameAnalysis(ctx, gameID)
+	if err != nil {
+		log.Printf("Error getting analysis: %v", err)
+		if errors.Is(err, store.ErrAnalysisNotFound) {
+			c.JSON(404, gin.H{"error": "Analysis not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, analysis)
+}
+
+// getGame 获取归档的对局记录
+func getGame(c *gin.Context) {
+	gameID := c.Param("id")
+
+	ctx := context.Background()
+	game, err := services.GetGame(ctx, gameID)
+	if err != nil {
+		log.Printf("Error getting game: %v", err)
+		if errors.Is(err, store.ErrGameNotFound) {
+			c.JSON(404, gin.H{"error": "Game not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, game)
+}
+
+// getGamePosition 获取归档对局第 move 步之后的局面，不传 move 时为终局
+func getGamePosition(c *gin.Context) {
+	gameID := c.Param("id")
+
+	var query struct {
+		Move *int `form:"move"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	position, err := services.GetGamePosition(ctx, gameID, query.Move)
+	if err != nil {
+		log.Printf("Error getting game position: %v", err)
+		if errors.Is(err, store.ErrGameNotFound) {
+			c.JSON(404, gin.H{"error": "Game not found"})
+			return
+		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, position)
+}
+
+// gameImage 返回按 format 绘制归档对局局面的处理函数（move, cell, numbers）
+func gameImage(format string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID := c.Param("id")
+
+		query := struct {
+			Move    *int `form:"move"`
+			Cell    int  `form:"cell"`
+			Numbers bool `form:"numbers"`
+		}{Numbers: true}
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := context.Background()
+		image, err := services.RenderGameImage(ctx, gameID, format, query.Move, query.Cell, query.Numbers)
+		if err != nil {
+			log.Printf("Error rendering game image: %v", err)
+			if errors.Is(err, store.ErrGameNotFound) {
+				c.JSON(404, gin.H{"error": "Game not found"})
+				return
+			}
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// 归档的对局不会修改，允许客户端和 CDN 缓存
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", image.Filename))
+		c.Data(200, image.ContentType, image.Content)
+	}
+}
+
+// gameReplay 把归档对局绘制为 GIF 动画（delay 毫秒, cell, numbers）
+func gameReplay(c *gin.Context) {
+	gameID := c.Param("id")
+
+	query := struct {
+		Delay   int  `form:"delay"`
+		Cell    int  `form:"cell"`
+		Numbers bool `form:"numbers"`
+	}{Numbers: true}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	replay, err := services.RenderGameReplay(ctx, gameID, time.Duration(query.Delay)*time.Millisecond, query.Cell, query.Numbers)
+	if err != nil {
+		log.Printf("Error rendering game replay: %v", err)
+		if errors.Is(err, store.ErrGameNotFound) {
+			c.JSON(404, gin.H{"error": "Game not found"})
+			return
+		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", replay.Filename))
+	c.Data(200, replay.ContentType, replay.Content)
+}
+
+// listUserGames 分页获取用户参与的对局（offset, limit）
+func listUserGames(c *gin.Context) {
+	userID := c.Param("userId")
+
+	var query struct {
+		Offset int `form:"offset"`
+		Limit  int `form:"limit"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	list, err := services.ListUserGames(ctx, userID, query.Offset, query.Limit)
+	if err != nil {
+		log.Printf("Error listing games: %v", err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// exportGame 导出对局记录（format: psq, sgf, renju）
+func exportGame(c *gin.Context) {
+	gameID := c.Param("id")
+	format := c.Query("format")
+
+	ctx := context.Background()
+	export, err := services.ExportGame(ctx, gameID, format)
+	if err != nil {
+		log.Printf("Error exporting game: %v", err)
+		if errors.Is(err, store.ErrGameNotFound) {
+			c.JSON(404, gin.H{"error": "Game not found"})
+			return
+		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	c.Data(200, export.ContentType, export.Content)
+}
+
+// errorStatus 根据服务层错误确定 HTTP 状态码
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidArgument), errors.Is(err, services.ErrInvalidMove),
+		errors.Is(err, services.ErrInvalidAction):
+		return 400
+	case errors.Is(err, store.ErrRoomNotFound):
+		return 404
+	case errors.Is(err, services.ErrRoomConflict):
+		return 409
+	case errors.Is(err, services.ErrBusy):
+		return 503
+	}
+	return 500
+}
+
 // handleWebPubSubOptions 处理 Web PubSub OPTIONS 请求
 func handleWebPubSubOptions(c *gin.Context) {
 	if origin := c.GetHeader("webhook-request-origin"); origin != "" {
@@ -193,16 +701,8 @@ func handleWebPubSubEvent(c *gin.Context) {
 	ctx := context.Background()
 
 	if eventType == "azure.webpubsub.sys.disconnected" {
-		log.Printf("User disconnected: %s", userID)
 		if userID != "" {
-			// 查找用户所在的房间并移除
-			room, err := services.FindRoomByUserID(ctx, userID)
-			if err == nil && room != nil {
-				_ = services.LeaveRoom(ctx, types.LeaveRoomRequest{
-					UserID: userID,
-					RoomID: room.ID,
-				})
-			}
+			handleUserDisconnected(userID)
 		}
 	} else if eventType == "azure.webpubsub.user.message" {
 		// 处理用户消息
@@ -212,7 +712,7 @@ func handleWebPubSubEvent(c *gin.Context) {
 
 			if msgType, ok := message["type"].(string); ok && msgType == "joinGroup" {
 				if group, ok := message["group"].(string); ok {
-					_ = config.AddUserToRoom(ctx, userID, group)
+					_ = broadcaster.AddUserToRoom(ctx, userID, group)
 					log.Printf("Added user %s to group %s via message", userID, group)
 				}
 			}
@@ -221,3 +721,17 @@ func handleWebPubSubEvent(c *gin.Context) {
 
 	c.Status(200)
 }
+
+// handleUserDisconnected 用户断开连接时，查找用户所在的房间并移除
+func handleUserDisconnected(userID string) {
+	log.Printf("User disconnected: %s", userID)
+
+	ctx := context.Background()
+	room, err := services.FindRoomByUserID(ctx, userID)
+	if err == nil && room != nil {
+		_ = services.LeaveRoom(ctx, types.LeaveRoomRequest{
+			UserID: userID,
+			RoomID: room.ID,
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package rules
+
+// 棋子颜色
+const (
+	Empty = 0
+	Black = 1
+	White = 2
+)
+
+// lineDirections 四个方向：水平、垂直、主对角线、副对角线
+var lineDirections = [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+
+// lineLength 计算 (row, col) 所在方向上同色连续棋子数（含自身）
+func lineLength(board [][]int, row, col, dr, dc, color int) int {
+	count := 1
+	for _, sign := range [2]int{1, -1} {
+		r, c := row+dr*sign, col+dc*sign
+		for inBoard(board, r, c) && board[r][c] == color {
+			count++
+			r += dr * sign
+			c += dc * sign
+		}
+	}
+	return count
+}
+
+// inBoard 判断坐标是否在棋盘内
+func inBoard(board [][]int, row, col int) bool {
+	return row >= 0 && row < len(board) && col >= 0 && col < len(board[row])
+}
@@ -0,0 +1,130 @@
+package rules
+
+// 连珠（Renju）规则：黑方禁止三三、四四和长连，白方五连及以上获胜。
+// 禁手判断遵循国际连珠规则：成五优先于禁手；活三指再下一手能成为活四的三，
+// 且该手本身不能是禁手（递归判断）。
+
+// Forbidden 禁手类型
+type Forbidden string
+
+const (
+	// NotForbidden 非禁手
+	NotForbidden Forbidden = ""
+	// DoubleThree 三三禁手
+	DoubleThree Forbidden = "double-three"
+	// DoubleFour 四四禁手
+	DoubleFour Forbidden = "double-four"
+	// Overline 长连禁手
+	Overline Forbidden = "overline"
+)
+
+// CheckForbidden 判断黑方在 (row, col) 落子是否为禁手，该点必须为空
+//
+// 判断过程中会临时修改 board，返回前恢复原状。
+func CheckForbidden(board [][]int, row, col int) Forbidden {
+	if board[row][col] != Empty {
+		return NotForbidden
+	}
+
+	board[row][col] = Black
+	defer func() { board[row][col] = Empty }()
+
+	return forbiddenAt(board, row, col)
+}
+
+// forbiddenAt 判断 (row, col) 处已放置的黑子是否构成禁手
+func forbiddenAt(board [][]int, row, col int) Forbidden {
+	// 成五优先
+	overline := false
+	for _, d := range lineDirections {
+		n := lineLength(board, row, col, d[0], d[1], Black)
+		if n == 5 {
+			return NotForbidden
+		}
+		if n > 5 {
+			overline = true
+		}
+	}
+	if overline {
+		return Overline
+	}
+
+	fours, threes := 0, 0
+	for _, d := range lineDirections {
+		f := countFours(board, row, col, d[0], d[1])
+		fours += f
+		if f == 0 && hasThree(board, row, col, d[0], d[1]) {
+			threes++
+		}
+	}
+
+	switch {
+	case fours >= 2:
+		return DoubleFour
+	case threes >= 2:
+		return DoubleThree
+	}
+	return NotForbidden
+}
+
+// fivePoints 返回该方向上能与 (row, col) 黑子一起恰好成五的空点（以偏移量表示，升序）
+func fivePoints(board [][]int, row, col, dr, dc int) []int {
+	var points []int
+	for offset := -4; offset <= 4; offset++ {
+		if offset == 0 {
+			continue
+		}
+		r, c := row+dr*offset, col+dc*offset
+		if !inBoard(board, r, c) || board[r][c] != Empty {
+			continue
+		}
+
+		board[r][c] = Black
+		if lineLength(board, row, col, dr, dc, Black) == 5 {
+			points = append(points, offset)
+		}
+		board[r][c] = Empty
+	}
+	return points
+}
+
+// countFours 计算该方向上包含 (row, col) 的四的个数
+//
+// 活四（.XXXX.）有两个成五点但只算一个四；同一直线上的 X.XXX.X 等形状算两个四。
+func countFours(board [][]int, row, col, dr, dc int) int {
+	points := fivePoints(board, row, col, dr, dc)
+	if isStraightFour(points) {
+		return 1
+	}
+	return len(points)
+}
+
+// isStraightFour 两个成五点相距 5 格，说明四子连续且两端皆可成五，即活四
+func isStraightFour(points []int) bool {
+	return len(points) == 2 && points[1]-points[0] == 5
+}
+
+// hasThree 判断该方向上是否存在包含 (row, col) 的活三
+//
+// 即存在一个空点，黑方在此落子后形成包含 (row, col) 的活四，且该落子本身不是禁手。
+func hasThree(board [][]int, row, col, dr, dc int) bool {
+	for offset := -4; offset <= 4; offset++ {
+		if offset == 0 {
+			continue
+		}
+		r, c := row+dr*offset, col+dc*offset
+		if !inBoard(board, r, c) || board[r][c] != Empty {
+			continue
+		}
+
+		board[r][c] = Black
+		straight := isStraightFour(fivePoints(board, row, col, dr, dc))
+		legal := straight && forbiddenAt(board, r, c) == NotForbidden
+		board[r][c] = Empty
+
+		if legal {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,540 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseBoard 把棋盘图转换为 15 路棋盘，返回棋盘和 '*' 所在的空点
+//
+// 'X' 为黑子，'O' 为白子，'.' 和 '*' 为空点；未写出的行和列都是空点。
+func parseBoard(t *testing.T, diagram string) ([][]int, int, int) {
+	t.Helper()
+	const size = 15
+	board := make([][]int, size)
+	for i := range board {
+		board[i] = make([]int, size)
+	}
+
+	row, col := -1, -1
+	lines := strings.Split(strings.Trim(diagram, "\n"), "\n")
+	for r, line := range lines {
+		for c, ch := range strings.TrimSpace(line) {
+			switch ch {
+			case 'X':
+				board[r][c] = Black
+			case 'O':
+				board[r][c] = White
+			case '*':
+				row, col = r, c
+			case '.':
+			default:
+				t.Fatalf("unexpected %q in diagram", ch)
+			}
+		}
+	}
+	if row == -1 {
+		t.Fatal("diagram has no '*'")
+	}
+	return board, row, col
+}
+
+// copyBoard 复制棋盘，用于检查判断后棋盘是否恢复原状
+func copyBoard(board [][]int) [][]int {
+	cp := make([][]int, len(board))
+	for i := range board {
+		cp[i] = append([]int(nil), board[i]...)
+	}
+	return cp
+}
+
+func TestCheckForbidden(t *testing.T) {
+	tests := []struct {
+		name    string
+		diagram string
+		want    Forbidden
+	}{
+		{
+			name: "open three in two directions",
+			diagram: `
+...............
+...............
+...............
+.......X.......
+.......X.......
+.....XX*.......`,
+			want: DoubleThree,
+		},
+		{
+			name: "split three and open three",
+			diagram: `
+...............
+...............
+...............
+.......X.......
+.......X.......
+....X.X*.......`,
+			want: DoubleThree,
+		},
+		{
+			name: "diagonal threes",
+			diagram: `
+...............
+...............
+...............
+.....X...X.....
+......X.X......
+.......*.......`,
+			want: DoubleThree,
+		},
+		{
+			name: "one three blocked by white",
+			diagram: `
+...............
+...............
+...............
+.......X.......
+.......X.......
+....OXX*.......`,
+			want: NotForbidden,
+		},
+		{
+			name: "one three blocked by the edge",
+			diagram: `
+.......X.......
+.......X.......
+.....XX*.......`,
+			want: NotForbidden,
+		},
+		{
+			name: "three that cannot become a straight four",
+			diagram: `
+...............
+...............
+...............
+.......X.......
+.......X.......
+...O.XX*.O.....`,
+			want: NotForbidden,
+		},
+		{
+			name: "three whose extension points are overlines",
+			diagram: `
+...............
+...............
+...............
+....X...X......
+....X...X......
+....X...X......
+....X...X......
+.....XX*.......
+....X..XX......
+.......X.......`,
+			want: NotForbidden,
+		},
+		{
+			name: "same shape without the overline stones",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+...............
+.....XX*.......
+.......X.......
+.......X.......`,
+			want: DoubleThree,
+		},
+		{
+			name: "four and three",
+			diagram: `
+...............
+...............
+...............
+.......X.......
+.......X.......
+....XXX*O......`,
+			want: NotForbidden,
+		},
+		{
+			name: "two fours",
+			diagram: `
+...............
+...............
+.......O.......
+.......X.......
+.......X.......
+.......X.......
+....XXX*O......`,
+			want: DoubleFour,
+		},
+		{
+			name: "two fours on one line",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+....X.X*X.X....`,
+			want: DoubleFour,
+		},
+		{
+			name: "two fours on one line with two stones apart",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+...XX.*X.XX....`,
+			want: DoubleFour,
+		},
+		{
+			name: "straight four is a single four",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+....XXX*.......`,
+			want: NotForbidden,
+		},
+		{
+			name: "overline",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+...XXX*XX......`,
+			want: Overline,
+		},
+		{
+			name: "five beats double three",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+.....X.X.......
+......XX.......
+...XXXX*.......`,
+			want: NotForbidden,
+		},
+		{
+			name: "five beats double four",
+			diagram: `
+...............
+...............
+...O...O.......
+....X..X.......
+.....X.X.......
+......XX.......
+...XXXX*O......`,
+			want: NotForbidden,
+		},
+		{
+			name: "five beats overline",
+			diagram: `
+...............
+...............
+...............
+.......X.......
+.......X.......
+.......X.......
+.......X.......
+....XXX*XX.....`,
+			want: NotForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, row, col := parseBoard(t, tt.diagram)
+			before := copyBoard(board)
+
+			if got := CheckForbidden(board, row, col); got != tt.want {
+				t.Errorf("CheckForbidden(%d, %d) = %q, want %q", row, col, got, tt.want)
+			}
+			if !reflect.DeepEqual(board, before) {
+				t.Error("CheckForbidden modified the board")
+			}
+		})
+	}
+}
+
+func TestCheckForbiddenOccupied(t *testing.T) {
+	board, row, col := parseBoard(t, `
+...XXX*XX......`)
+	board[row][col] = White
+	if got := CheckForbidden(board, row, col); got != NotForbidden {
+		t.Errorf("CheckForbidden on an occupied point = %q, want no forbidden", got)
+	}
+}
+
+func TestRuleSetCheckForbidden(t *testing.T) {
+	diagrams := map[Forbidden]string{
+		DoubleThree: `
+...............
+...............
+...............
+.......X.......
+.......X.......
+.....XX*.......`,
+		DoubleFour: `
+....X.X*X.X....`,
+		Overline: `
+...XXX*XX......`,
+	}
+
+	for want, diagram := range diagrams {
+		for _, name := range []string{Freestyle, Standard, Caro, Renju} {
+			ruleSet, err := Get(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, color := range []int{Black, White} {
+				board, row, col := parseBoard(t, diagram)
+				if color == White {
+					// 同样的形状换成白子
+					for r := range board {
+						for c := range board[r] {
+							if board[r][c] == Black {
+								board[r][c] = White
+							}
+						}
+					}
+				}
+
+				expected := NotForbidden
+				if name == Renju && color == Black {
+					expected = want
+				}
+				if got := ruleSet.CheckForbidden(board, row, col, color); got != expected {
+					t.Errorf("%s: %s color %d = %q, want %q", name, want, color, got, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestIsWin(t *testing.T) {
+	tests := []struct {
+		name    string
+		diagram string
+		color   int
+		want    map[string]bool // 规则名称 -> 是否获胜
+	}{
+		{
+			name: "open five",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+....XX*XX......`,
+			color: Black,
+			want:  map[string]bool{Freestyle: true, Standard: true, Caro: true, Renju: true},
+		},
+		{
+			name: "diagonal five",
+			diagram: `
+...............
+..X............
+...X...........
+....*..........
+.....X.........
+......X........`,
+			color: Black,
+			want:  map[string]bool{Freestyle: true, Standard: true, Caro: true, Renju: true},
+		},
+		{
+			name: "black overline",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+...XXX*XX......`,
+			color: Black,
+			want:  map[string]bool{Freestyle: true, Standard: false, Caro: true, Renju: false},
+		},
+		{
+			name: "white overline",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+...OOO*OO......`,
+			color: White,
+			want:  map[string]bool{Freestyle: true, Standard: false, Caro: true, Renju: true},
+		},
+		{
+			name: "five blocked at both ends",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+...OXX*XXO.....`,
+			color: Black,
+			want:  map[string]bool{Freestyle: true, Standard: true, Caro: false, Renju: true},
+		},
+		{
+			name: "five blocked at one end",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+...OXX*XX......`,
+			color: Black,
+			want:  map[string]bool{Freestyle: true, Standard: true, Caro: true, Renju: true},
+		},
+		{
+			name: "five between the edge and a white stone",
+			diagram: `
+XX*XXO.........`,
+			color: Black,
+			want:  map[string]bool{Freestyle: true, Standard: true, Caro: true, Renju: true},
+		},
+		{
+			name: "broken five",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+...XX*X.X......`,
+			color: Black,
+			want:  map[string]bool{Freestyle: false, Standard: false, Caro: false, Renju: false},
+		},
+		{
+			name: "four",
+			diagram: `
+...............
+...............
+...............
+...............
+...............
+...............
+....XX*X.......`,
+			color: Black,
+			want:  map[string]bool{Freestyle: false, Standard: false, Caro: false, Renju: false},
+		},
+	}
+
+	for _, tt := range tests {
+		for name, want := range tt.want {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				ruleSet, err := Get(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				board, row, col := parseBoard(t, tt.diagram)
+				board[row][col] = tt.color
+
+				if got := ruleSet.IsWin(board, row, col); got != want {
+					t.Errorf("IsWin = %v, want %v", got, want)
+				}
+				line := ruleSet.WinningLine(board, row, col)
+				if (line != nil) != want {
+					t.Errorf("WinningLine = %v, want a line: %v", line, want)
+				}
+			})
+		}
+	}
+}
+
+func TestWinningLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		diagram string
+		want    []Point
+	}{
+		{
+			name:  "horizontal",
+			rules: Standard,
+			diagram: `
+...............
+...............
+..XX*XX........`,
+			want: []Point{{2, 2}, {2, 3}, {2, 4}, {2, 5}, {2, 6}},
+		},
+		{
+			name:  "anti-diagonal from the top end",
+			rules: Renju,
+			diagram: `
+......X........
+.....X.........
+....*..........
+...X...........
+..X............`,
+			want: []Point{{0, 6}, {1, 5}, {2, 4}, {3, 3}, {4, 2}},
+		},
+		{
+			name:  "overline in freestyle",
+			rules: Freestyle,
+			diagram: `
+X..............
+X..............
+*..............
+X..............
+X..............
+X..............`,
+			want: []Point{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}},
+		},
+		{
+			name:  "exact five beside an overline",
+			rules: Standard,
+			diagram: `
+...............
+..X............
+..X............
+..X............
+..X............
+XX*XXX.........`,
+			want: []Point{{1, 2}, {2, 2}, {3, 2}, {4, 2}, {5, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleSet, err := Get(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			board, row, col := parseBoard(t, tt.diagram)
+			board[row][col] = Black
+
+			if got := ruleSet.WinningLine(board, row, col); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WinningLine = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package rules
+
+import "fmt"
+
+// 规则名称
+const (
+	// Freestyle 无禁手，五连及以上获胜
+	Freestyle = "freestyle"
+	// Standard 标准五子棋，恰好五连获胜，长连不算
+	Standard = "standard"
+	// Caro 五连及以上获胜，但两端都被对方堵住的五连不算
+	Caro = "caro"
+	// Renju 连珠规则，黑方有禁手
+	Renju = "renju"
+)
+
+// 禁手处理方式
+const (
+	// ForbiddenReject 拒绝禁手落子
+	ForbiddenReject = "reject"
+	// ForbiddenLose 禁手落子判负
+	ForbiddenLose = "lose"
+)
+
+// RuleSet 胜负规则
+type RuleSet interface {
+	// Name 规则名称
+	Name() string
+	// CheckForbidden 判断 color 方在 (row, col) 落子是否为禁手，该点必须为空
+	CheckForbidden(board [][]int, row, col, color int) Forbidden
+	// IsWin 判断刚落在 (row, col) 的棋子是否获胜
+	IsWin(board [][]int, row, col int) bool
+	// WinningLine 返回刚落在 (row, col) 的棋子获胜的那条连子，从一端到另一端；不获胜时返回 nil
+	WinningLine(board [][]int, row, col int) []Point
+}
+
+// Point 棋盘坐标
+type Point struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+var ruleSets = map[string]RuleSet{
+	Freestyle: freestyleRules{},
+	Standard:  standardRules{},
+	Caro:      caroRules{},
+	Renju:     renjuRules{},
+}
+
+// Get 根据名称获取规则，空字符串视为 Freestyle
+func Get(name string) (RuleSet, error) {
+	if name == "" {
+		name = Freestyle
+	}
+	rs, ok := ruleSets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rules %q", name)
+	}
+	return rs, nil
+}
+
+// freestyleRules 无禁手，五连及以上获胜
+type freestyleRules struct{}
+
+func (freestyleRules) Name() string { return Freestyle }
+
+func (freestyleRules) CheckForbidden(board [][]int, row, col, color int) Forbidden {
+	return NotForbidden
+}
+
+func (freestyleRules) IsWin(board [][]int, row, col int) bool {
+	return anyLine(board, row, col, fiveOrMore)
+}
+
+func (freestyleRules) WinningLine(board [][]int, row, col int) []Point {
+	return winningLine(board, row, col, fiveOrMore)
+}
+
+// standardRules 恰好五连获胜
+type standardRules struct{}
+
+func (standardRules) Name() string { return Standard }
+
+func (standardRules) CheckForbidden(board [][]int, row, col, color int) Forbidden {
+	return NotForbidden
+}
+
+func (standardRules) IsWin(board [][]int, row, col int) bool {
+	return anyLine(board, row, col, exactlyFive)
+}
+
+func (standardRules) WinningLine(board [][]int, row, col int) []Point {
+	return winningLine(board, row, col, exactlyFive)
+}
+
+// caroRules 五连及以上获胜，两端都被对方棋子堵住时不算（棋盘边缘不算堵）
+type caroRules struct{}
+
+func (caroRules) Name() string { return Caro }
+
+func (caroRules) CheckForbidden(board [][]int, row, col, color int) Forbidden {
+	return NotForbidden
+}
+
+func (caroRules) IsWin(board [][]int, row, col int) bool {
+	return anyLine(board, row, col, unblockedFive)
+}
+
+func (caroRules) WinningLine(board [][]int, row, col int) []Point {
+	return winningLine(board, row, col, unblockedFive)
+}
+
+// renjuRules 黑方恰好五连获胜且有禁手，白方五连及以上获胜
+type renjuRules struct{}
+
+func (renjuRules) Name() string { return Renju }
+
+func (renjuRules) CheckForbidden(board [][]int, row, col, color int) Forbidden {
+	if color != Black {
+		return NotForbidden
+	}
+	return CheckForbidden(board, row, col)
+}
+
+func (renjuRules) IsWin(board [][]int, row, col int) bool {
+	if board[row][col] == Black {
+		return anyLine(board, row, col, exactlyFive)
+	}
+	return anyLine(board, row, col, fiveOrMore)
+}
+
+func (renjuRules) WinningLine(board [][]int, row, col int) []Point {
+	if board[row][col] == Black {
+		return winningLine(board, row, col, exactlyFive)
+	}
+	return winningLine(board, row, col, fiveOrMore)
+}
+
+// 各规则的获胜条件，n 为连子数，blockedEnds 为被对方堵住的端数
+func fiveOrMore(n, blockedEnds int) bool    { return n >= 5 }
+func exactlyFive(n, blockedEnds int) bool   { return n == 5 }
+func unblockedFive(n, blockedEnds int) bool { return n >= 5 && blockedEnds < 2 }
+
+// anyLine 对 (row, col) 所在的四个方向分别计算连子数和被对方堵住的端数，任一方向满足 win 即返回 true
+func anyLine(board [][]int, row, col int, win func(n, blockedEnds int) bool) bool {
+	_, ok := winningDirection(board, row, col, win)
+	return ok
+}
+
+// winningDirection 返回 (row, col) 所在连子满足 win 的第一个方向
+func winningDirection(board [][]int, row, col int, win func(n, blockedEnds int) bool) ([2]int, bool) {
+	color := board[row][col]
+	for _, d := range lineDirections {
+		n := lineLength(board, row, col, d[0], d[1], color)
+		if win(n, blockedEnds(board, row, col, d[0], d[1], color)) {
+			return d, true
+		}
+	}
+	return [2]int{}, false
+}
+
+// winningLine 返回 (row, col) 所在满足 win 的连子的所有棋子，不满足时返回 nil
+func winningLine(board [][]int, row, col int, win func(n, blockedEnds int) bool) []Point {
+	d, ok := winningDirection(board, row, col, win)
+	if !ok {
+		return nil
+	}
+
+	// 退到连子的一端，再沿方向收集
+	color := board[row][col]
+	r, c := row, col
+	for inBoard(board, r-d[0], c-d[1]) && board[r-d[0]][c-d[1]] == color {
+		r -= d[0]
+		c -= d[1]
+	}
+	var line []Point
+	for inBoard(board, r, c) && board[r][c] == color {
+		line = append(line, Point{Row: r, Col: c})
+		r += d[0]
+		c += d[1]
+	}
+	return line
+}
+
+// blockedEnds 计算 (row, col) 所在连子两端被对方棋子占据的端数
+func blockedEnds(board [][]int, row, col, dr, dc, color int) int {
+	blocked := 0
+	for _, sign := range [2]int{1, -1} {
+		r, c := row, col
+		for inBoard(board, r+dr*sign, c+dc*sign) && board[r+dr*sign][c+dc*sign] == color {
+			r += dr * sign
+			c += dc * sign
+		}
+		r += dr * sign
+		c += dc * sign
+		if inBoard(board, r, c) && board[r][c] != Empty && board[r][c] != color {
+			blocked++
+		}
+	}
+	return blocked
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"gomoku-backend/ai"
+	"gomoku-backend/rules"
+	"gomoku-backend/types"
+)
+
+// 分析结论
+const (
+	VerdictWin     = "win"
+	VerdictLoss    = "loss"
+	VerdictUnclear = "unclear"
+)
+
+// 候选点数量
+const (
+	DefaultAnalysisTopN = 3
+	MaxAnalysisTopN     = 10
+)
+
+// analysisLevel 提示和局面分析使用的引擎难度
+const analysisLevel = ai.Hard
+
+// searchSlots 同时进行的局面分析、提示和解题搜索的名额，默认为 CPU 核数的一半
+//
+// 每次搜索占满一个核数秒，名额用完时直接拒绝新的请求，不排队等待。
+var searchSlots = make(chan struct{}, max(1, runtime.NumCPU()/2))
+
+// SetSearchConcurrency 设置同时进行的局面分析、提示和解题搜索数
+func SetSearchConcurrency(n int) {
+	searchSlots = make(chan struct{}, n)
+}
+
+// acquireSearch 占用一个搜索名额，搜索结束后需调用 release；名额已满时返回 ErrBusy
+func acquireSearch() (release func(), err error) {
+	slots := searchSlots
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	default:
+		return nil, ErrBusy
+	}
+}
+
+// GetHint 为轮到落子的玩家给出提示，排位对局不可用
+func GetHint(ctx context.Context, roomID, userID string) (*types.PositionAnalysis, error) {
+	room, err := GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.Rated {
+		return nil, fmt.Errorf("%w: hints are disabled in rated games", ErrInvalidAction)
+	}
+	if room.Status != "playing" {
+		return nil, fmt.Errorf("%w: game is not in playing status", ErrInvalidAction)
+	}
+	player := findPlayer(room, userID)
+	if player == nil {
+		return nil, fmt.Errorf("%w: only players can ask for a hint", ErrInvalidAction)
+	}
+	if player.Color != room.CurrentPlayer {
+		return nil, fmt.Errorf("%w: hints are only available on your turn", ErrInvalidAction)
+	}
+
+	return analyzePosition(room.Rules, room.Board, room.CurrentPlayer, DefaultAnalysisTopN)
+}
+
+// AnalyzePosition 分析任意局面，不涉及房间
+func AnalyzePosition(ctx context.Context, req types.AnalyzeRequest) (*types.PositionAnalysis, error) {
+	ruleSet, err := rules.Get(req.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	topN := req.TopN
+	if topN == 0 {
+		topN = DefaultAnalysisTopN
+	}
+	if topN < 1 || topN > MaxAnalysisTopN {
+		return nil, fmt.Errorf("%w: topN must be between 1 and %d", ErrInvalidArgument, MaxAnalysisTopN)
+	}
+
+	board, color, err := requestPosition(req.Board, req.Moves, req.BoardSize, req.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	return analyzePosition(ruleSet.Name(), board, color, topN)
+}
+
+// requestPosition 根据请求中的棋盘或着法得到局面和行棋方，color 为 0 时根据双方子数推断
+func requestPosition(board [][]int, moves []types.Move, boardSize, color int) ([][]int, int, error) {
+	switch {
+	case board != nil && moves != nil:
+		return nil, 0, fmt.Errorf("%w: board and moves cannot both be set", ErrInvalidArgument)
+	case board != nil:
+		if err := validateBoard(board); err != nil {
+			return nil, 0, err
+		}
+	default:
+		var err error
+		board, err = boardFromMoves(boardSize, moves)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if color == 0 {
+		color = sideToMove(board)
+	}
+	if color != 1 && color != 2 {
+		return nil, 0, fmt.Errorf("%w: color must be 1 or 2", ErrInvalidArgument)
+	}
+	return board, color, nil
+}
+
+// analyzePosition 搜索局面并整理为分析结果
+func analyzePosition(ruleName string, board [][]int, color, topN int) (*types.PositionAnalysis, error) {
+	ruleSet, err := rules.Get(ruleName)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ai.LevelConfig(analysisLevel)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MultiPV = topN
+
+	release, err := acquireSearch()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result, err := ai.NewEngine(ruleSet, cfg).Search(board, color)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	analysis := &types.PositionAnalysis{
+		Color:   color,
+		Verdict: VerdictUnclear,
+		Best:    types.CandidateMove(result.Best),
+		PV:      pvMoves(result.PV, color),
+		Depth:   result.Depth,
+		Nodes:   result.Nodes,
+	}
+	for i, c := range result.Candidates {
+		if i >= topN {
+			break
+		}
+		analysis.Candidates = append(analysis.Candidates, types.CandidateMove(c))
+	}
+
+	switch {
+	case result.IsWin():
+		analysis.Verdict = VerdictWin
+		analysis.ForcedWin = analysis.PV
+	case result.IsLoss():
+		analysis.Verdict = VerdictLoss
+		analysis.ForcedWin = analysis.PV
+	}
+	return analysis, nil
+}
+
+// pvMoves 把主要变化转换为棋步，color 为第一步的执子方
+func pvMoves(pv []ai.Move, color int) []types.Move {
+	moves := make([]types.Move, len(pv))
+	for i, m := range pv {
+		moves[i] = types.Move{Row: m.Row, Col: m.Col, Player: color}
+		color = opponentColor(color)
+	}
+	return moves
+}
+
+// validateBoard 校验棋盘为合法大小的方阵且只含 0、1、2
+func validateBoard(board [][]int) error {
+	size := len(board)
+	if size < MinBoardSize || size > MaxBoardSize {
+		return fmt.Errorf("%w: boardSize must be between %d and %d", ErrInvalidArgument, MinBoardSize, MaxBoardSize)
+	}
+	for _, row := range board {
+		if len(row) != size {
+			return fmt.Errorf("%w: board must be square", ErrInvalidArgument)
+		}
+		for _, cell := range row {
+			if cell < 0 || cell > 2 {
+				return fmt.Errorf("%w: board cells must be 0, 1 or 2", ErrInvalidArgument)
+			}
+		}
+	}
+	return nil
+}
+
+// boardFromMoves 在空棋盘上依次摆放棋步，player 为 0 的棋步按黑白交替
+func boardFromMoves(size int, moves []types.Move) ([][]int, error) {
+	if size == 0 {
+		size = DefaultBoardSize
+	}
+	if size < MinBoardSize || size > MaxBoardSize {
+		return nil, fmt.Errorf("%w: boardSize must be between %d and %d", ErrInvalidArgument, MinBoardSize, MaxBoardSize)
+	}
+
+	board := newBoard(size)
+	color := 1
+	for i, m := range moves {
+		if m.Player != 0 {
+			color = m.Player
+		}
+		if color != 1 && color != 2 {
+			return nil, fmt.Errorf("%w: move %d: player must be 1 or 2", ErrInvalidArgument, i+1)
+		}
+		if m.Row < 0 || m.Row >= size || m.Col < 0 || m.Col >= size {
+			return nil, fmt.Errorf("%w: move %d: position (%d, %d) is outside the %dx%d board", ErrInvalidArgument, i+1, m.Row, m.Col, size, size)
+		}
+		if board[m.Row][m.Col] != 0 {
+			return nil, fmt.Errorf("%w: move %d: position (%d, %d) is already occupied", ErrInvalidArgument, i+1, m.Row, m.Col)
+		}
+		board[m.Row][m.Col] = color
+		color = opponentColor(color)
+	}
+	return board, nil
+}
+
+// sideToMove 根据双方子数推断行棋方，子数相等时黑方先行
+func sideToMove(board [][]int) int {
+	var counts [3]int
+	for _, row := range board {
+		for _, cell := range row {
+			counts[cell]++
+		}
+	}
+	if counts[1] > counts[2] {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"gomoku-backend/store"
+	"gomoku-backend/types"
+)
+
+// 对局列表分页
+const (
+	defaultGamePageSize = 20
+	maxGamePageSize     = 100
+)
+
+// gameStore 对局记录存储，未设置时不归档对局
+var gameStore store.GameStore
+
+// SetGameStore 设置对局记录存储
+func SetGameStore(s store.GameStore) {
+	gameStore = s
+}
+
+// GetGame 获取归档的对局记录
+func GetGame(ctx context.Context, gameID string) (*types.GameRecord, error) {
+	if gameStore == nil {
+		return nil, store.ErrGameNotFound
+	}
+	return gameStore.GetGame(ctx, gameID)
+}
+
+// ListUserGames 分页获取用户参与的对局，limit 为 0 时使用默认每页条数
+func ListUserGames(ctx context.Context, userID string, offset, limit int) (*types.GameList, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidArgument)
+	}
+	if limit == 0 {
+		limit = defaultGamePageSize
+	}
+	if limit < 1 || limit > maxGamePageSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidArgument, maxGamePageSize)
+	}
+
+	list := &types.GameList{Games: []types.GameRecord{}, Offset: offset, Limit: limit}
+	if gameStore == nil {
+		return list, nil
+	}
+
+	games, total, err := gameStore.ListUserGames(ctx, userID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	list.Games = games
+	list.Total = total
+	return list, nil
+}
+
+// archiveGame 把房间刚结束的一局保存为对局记录，房间删除或开始下一局后记录仍然保留
+//
+// 着法取自 room.MoveHistory，需在房间开始下一局或重置之前调用。
+func archiveGame(ctx context.Context, room *types.GameRoom) {
+	if gameStore == nil || len(room.Series) == 0 {
+		return
+	}
+
+	summary := room.Series[len(room.Series)-1]
+	game := &types.GameRecord{
+		ID:              summary.ID,
+		RoomID:          room.ID,
+		RoomNumber:      room.RoomNumber,
+		GameNumber:      summary.GameNumber,
+		Players:         summary.Players,
+		BoardSize:       len(room.Board),
+		Rules:           room.Rules,
+		ForbiddenPolicy: room.ForbiddenPolicy,
+		Opening:         room.Opening,
+		OpeningMoves:    summary.OpeningMoves,
+		TimeControl:     room.TimeControl,
+		Rated:           room.Rated,
+		Bot:             room.Bot,
+		MoveHistory:     room.MoveHistory,
+		WinnerID:        summary.WinnerID,
+		WinnerColor:     summary.WinnerColor,
+		EndReason:       summary.EndReason,
+		WinLine:         summary.WinLine,
+		StartTime:       summary.StartTime,
+		EndTime:         summary.EndTime,
+	}
+	for _, p := range summary.Players {
+		game.PlayerIDs = append(game.PlayerIDs, p.UserID)
+	}
+	if game.MoveHistory == nil {
+		game.MoveHistory = []types.Move{}
+	}
+
+	if err := gameStore.CreateGame(ctx, game); err != nil {
+		log.Printf("Error archiving game %s in room %s: %v", game.ID, room.ID, err)
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gomoku-backend/ai"
+	"gomoku-backend/rules"
+	"gomoku-backend/store"
+	"gomoku-backend/types"
+)
+
+// 电脑玩家
+const (
+	BotUserID   = "bot"
+	BotNickname = "电脑"
+)
+
+// botThinking 正在为其计算着法的房间，避免定时器和定期检查重复触发
+var botThinking = struct {
+	sync.Mutex
+	rooms map[string]bool
+}{rooms: make(map[string]bool)}
+
+// validateBot 校验人机对局设置，返回补全默认值后的设置，nil 表示不是人机对局
+func validateBot(cfg *types.BotConfig, req types.CreateRoomRequest) (*types.BotConfig, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Engine != "" {
+		if _, ok := externalEngines[cfg.Engine]; !ok {
+			return nil, fmt.Errorf("%w: unknown engine %q", ErrInvalidArgument, cfg.Engine)
+		}
+	} else if _, err := ai.LevelConfig(ai.Level(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("%w: bot level must be easy, medium or hard", ErrInvalidArgument)
+	}
+
+	bot := *cfg
+	if bot.Color == 0 {
+		bot.Color = 2
+	}
+	if bot.Color != 1 && bot.Color != 2 {
+		return nil, fmt.Errorf("%w: bot color must be 1 or 2", ErrInvalidArgument)
+	}
+	if req.UserID == BotUserID {
+		return nil, fmt.Errorf("%w: userId %q is reserved", ErrInvalidArgument, BotUserID)
+	}
+	if req.Opening != "" {
+		return nil, fmt.Errorf("%w: openings are not supported in bot games", ErrInvalidArgument)
+	}
+	if req.Rated {
+		return nil, fmt.Errorf("%w: bot games cannot be rated", ErrInvalidArgument)
+	}
+	return &bot, nil
+}
+
+// newBotPlayer 创建电脑玩家，使用外部引擎时以引擎名称作为昵称
+func newBotPlayer(bot *types.BotConfig) types.Player {
+	nickname := BotNickname
+	if bot.Engine != "" {
+		nickname = bot.Engine
+	}
+	return types.Player{
+		UserID:   BotUserID,
+		Nickname: nickname,
+		Color:    bot.Color,
+		IsReady:  true,
+		IsBot:    true,
+	}
+}
+
+// isBotTurn 判断是否轮到电脑落子
+func isBotTurn(room *types.GameRoom) bool {
+	if room.Status != "playing" {
+		return false
+	}
+	player := playerByColor(room, room.CurrentPlayer)
+	return player != nil && player.IsBot
+}
+
+// againstBot 判断 userID 的对手是否是电脑
+func againstBot(room *types.GameRoom, userID string) bool {
+	opponent := otherPlayer(room, userID)
+	return opponent != nil && opponent.IsBot
+}
+
+// humanPlayers 返回房间内真人玩家的数量
+func humanPlayers(room *types.GameRoom) int {
+	count := 0
+	for _, p := range room.Players {
+		if !p.IsBot {
+			count++
+		}
+	}
+	return count
+}
+
+// PlayBotMove 轮到电脑时计算并落子
+//
+// 由房间定时器在对方落子后立即触发。计算期间局面被悔棋等操作改变时重新计算。
+// 没有可落子的位置或外部引擎失败时电脑认输。
+func PlayBotMove(ctx context.Context, roomID string) error {
+	botThinking.Lock()
+	if botThinking.rooms[roomID] {
+		botThinking.Unlock()
+		return nil
+	}
+	botThinking.rooms[roomID] = true
+	botThinking.Unlock()
+
+	defer func() {
+		botThinking.Lock()
+		delete(botThinking.rooms, roomID)
+		botThinking.Unlock()
+	}()
+
+	for {
+		room, err := GetRoom(ctx, roomID)
+		if err != nil {
+			if errors.Is(err, store.ErrRoomNotFound) {
+				return nil
+			}
+			return err
+		}
+		if !isBotTurn(room) {
+			return nil
+		}
+
+		move, err := chooseBotMove(room)
+		if errors.Is(err, ai.ErrNoMove) || errors.Is(err, errEngineFailed) {
+			// 只剩禁手点或外部引擎失败时认输
+			log.Printf("Bot resigns in room %s: %v", roomID, err)
+			_, err = Resign(ctx, types.ResignRequest{UserID: BotUserID, RoomID: roomID})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		latest, err := GetRoom(ctx, roomID)
+		if err != nil {
+			return err
+		}
+		if len(latest.MoveHistory) != len(room.MoveHistory) || latest.Status != room.Status {
+			continue
+		}
+
+		_, err = MakeMove(ctx, types.MakeMoveRequest{
+			UserID: BotUserID,
+			RoomID: roomID,
+			Row:    move.Row,
+			Col:    move.Col,
+		})
+		return err
+	}
+}
+
+// chooseBotMove 为电脑选择着法，设置了外部引擎时由外部引擎落子
+func chooseBotMove(room *types.GameRoom) (ai.Move, error) {
+	if room.Bot.Engine != "" {
+		return externalBotMove(room)
+	}
+	result, err := searchBotMove(room)
+	if err != nil {
+		return ai.Move{}, err
+	}
+	return ai.Move{Row: result.Best.Row, Col: result.Best.Col}, nil
+}
+
+// searchBotMove 按房间的难度设置为电脑搜索着法
+//
+// 设置了种子时只按节点数限制搜索，计时对局中也不按剩余时间截止，着法可以复现；
+// 否则在难度的时间预算内搜索，计时对局中最多使用剩余时间的一半。
+func searchBotMove(room *types.GameRoom) (*ai.Result, error) {
+	ruleSet, err := rules.Get(room.Rules)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ai.LevelConfig(ai.Level(room.Bot.Level))
+	if err != nil {
+		return nil, err
+	}
+
+	if room.Bot.Seed != 0 {
+		cfg.Seed = room.Bot.Seed + int64(len(room.MoveHistory))
+		cfg.TimeBudget = 0
+	} else {
+		cfg.Seed = time.Now().UnixNano()
+	}
+
+	if room.Bot.Seed == 0 && room.Clock != nil && room.TimeControl != nil {
+		left := time.Until(clockDeadline(room)) / 2
+		if cfg.TimeBudget == 0 || left < cfg.TimeBudget {
+			cfg.TimeBudget = left
+		}
+		if cfg.TimeBudget <= 0 {
+			cfg.TimeBudget = time.Millisecond
+		}
+	}
+
+	start := time.Now()
+	result, err := ai.NewEngine(ruleSet, cfg).Search(room.Board, room.CurrentPlayer)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Bot (%s) in room %s chose %d,%d (score %d, depth %d, %d nodes, %v)",
+		room.Bot.Level, room.ID, result.Best.Row, result.Best.Col, result.Best.Score, result.Depth, result.Nodes, time.Since(start))
+	return result, nil
+}
@@ -2,24 +2,86 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
 	"time"
 
-	"gomoku-backend/config"
+	"gomoku-backend/store"
 	"gomoku-backend/types"
-
-	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 )
 
 // LeaveRoom 离开房间
 func LeaveRoom(ctx context.Context, req types.LeaveRoomRequest) error {
+	var (
+		room     *types.GameRoom
+		finished *types.GameRoom
+		left     bool
+		deleted  bool
+	)
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		room, finished, left, deleted, err = leaveRoom(ctx, req)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !left {
+		return nil // 房间不存在或用户不在房间
+	}
+
+	if deleted {
+		cancelRoomTimer(room.ID)
+		closeRoomEngine(room.ID)
+	} else {
+		scheduleRoomTimer(room)
+	}
+
+	// 从 PubSub 组移除
+	_ = broadcaster.RemoveUserFromRoom(ctx, req.UserID, room.ID)
+
+	if finished != nil {
+		// 中途离开的一局判对方获胜：先推送结束时的房间（含 result），归档并推送比赛结果，再推送房间删除或回到等待状态
+		log.Printf("User %s abandoned the game in room %s", req.UserID, room.ID)
+		_ = broadcaster.SendToRoom(ctx, room.ID, types.PubSubMessage{
+			Type: "game_update",
+			Data: finished,
+		})
+		gameFinished(ctx, finished)
+	}
+
+	if deleted {
+		// 踢出所有旁观者
+		for _, spectator := range room.Spectators {
+			_ = broadcaster.RemoveUserFromRoom(ctx, spectator.UserID, room.ID)
+		}
+
+		// 通知房间即将销毁
+		_ = broadcaster.SendToRoom(ctx, room.ID, types.PubSubMessage{
+			Type: "room_deleted",
+			Data: map[string]string{"roomId": room.ID},
+		})
+	} else {
+		// 通知更新
+		_ = broadcaster.SendToRoom(ctx, room.ID, types.PubSubMessage{
+			Type: "room_update",
+			Data: room,
+		})
+	}
+
+	return nil
+}
+
+// leaveRoom 执行一次离开房间的读-改-写，返回离开后的房间、用户是否在房间中以及房间是否被删除
+//
+// 玩家中途离开对局时还返回这一局结束时的房间（离开和重置之前），否则为 nil。
+func leaveRoom(ctx context.Context, req types.LeaveRoomRequest) (*types.GameRoom, *types.GameRoom, bool, bool, error) {
 	room, err := GetRoom(ctx, req.RoomID)
 	if err != nil {
 		// 尝试通过 userId 查找
 		room, err = FindRoomByUserID(ctx, req.UserID)
 		if err != nil || room == nil {
-			return nil // 房间不存在或用户不在房间
+			return nil, nil, false, false, nil
 		}
 	}
 
@@ -44,11 +106,17 @@ func LeaveRoom(ctx context.Context, req types.LeaveRoomRequest) error {
 	}
 
 	if playerIndex == -1 && spectatorIndex == -1 {
-		return nil // 用户不在房间
+		return nil, nil, false, false, nil
 	}
 
-	oldStatus := room.Status
-	shouldDelete := false
+	// 对局进行中的玩家离开，判对方获胜
+	var finished *types.GameRoom
+	if playerIndex != -1 && (room.Status == "playing" || room.Status == "opening") && len(room.Players) == 2 {
+		room.OpeningState = nil
+		finishGame(room, opponentColor(room.Players[playerIndex].Color), EndReasonAbandon, nil)
+		room.LastActionTime = time.Now()
+		finished = snapshotRoom(room)
+	}
 
 	// 移除用户
 	if playerIndex != -1 {
@@ -57,135 +125,138 @@ func LeaveRoom(ctx context.Context, req types.LeaveRoomRequest) error {
 		room.Spectators = append(room.Spectators[:spectatorIndex], room.Spectators[spectatorIndex+1:]...)
 	}
 
-	// 从 PubSub 组移除
-	_ = config.RemoveUserFromRoom(ctx, req.UserID, room.ID)
-
-	// 检查是否需要删除房间
-	if len(room.Players) == 0 {
-		shouldDelete = true
+	// 没有真人玩家时删除房间
+	if humanPlayers(room) == 0 {
+		if err := roomStore.DeleteRoom(ctx, room); err != nil {
+			return nil, nil, false, false, err
+		}
+		return room, finished, true, true, nil
 	}
 
-	container := config.GetContainer()
-
-	if shouldDelete {
-		// 踢出所有旁观者
-		for _, spectator := range room.Spectators {
-			_ = config.RemoveUserFromRoom(ctx, spectator.UserID, room.ID)
+	// 如果玩家离开导致状态变化（已结束的对局也回到等待状态，以便新玩家加入后重新开始）
+	if room.Status != "waiting" && room.Status != "review" && len(room.Players) < 2 {
+		room.Status = "waiting"
+		// 重置游戏盘面
+		room.Board = newBoard(len(room.Board))
+		room.MoveHistory = []types.Move{}
+		room.CurrentPlayer = 1
+		setResult(room, nil)
+		room.StartTime = nil
+		room.Clock = nil
+		room.DrawOffer = nil
+		room.TakebackOffer = nil
+		room.RematchOffer = nil
+		room.OpeningState = nil
+		room.OpeningMoves = 0
+		if room.Match != nil {
+			resetMatch(room.Match)
 		}
-
-		// 通知房间即将销毁
-		_ = config.SendToRoom(ctx, room.ID, types.PubSubMessage{
-			Type: "room_deleted",
-			Data: map[string]string{"roomId": room.ID},
-		})
-
-		// 删除房间
-		partitionKey := azcosmos.NewPartitionKeyString(oldStatus)
-		_, _ = container.DeleteItem(ctx, partitionKey, room.ID, nil)
-	} else {
-		// 如果玩家离开导致状态变化
-		if room.Status == "playing" && len(room.Players) < 2 {
-			room.Status = "waiting"
-			// 重置游戏盘面
-			board := make([][]int, 15)
-			for i := range board {
-				board[i] = make([]int, 15)
-			}
-			room.Board = board
-			room.MoveHistory = []types.Move{}
-			room.CurrentPlayer = 1
-			room.Winner = nil
-			// 剩下的玩家重置
-			if len(room.Players) > 0 {
-				room.Players[0].Color = 1
-				room.Players[0].IsReady = true
-			}
+		// 剩下的玩家重置
+		if len(room.Players) > 0 {
+			room.Players[0].Color = 1
+			room.Players[0].IsReady = true
+			room.Players[0].TakebacksUsed = 0
 		}
+	}
 
-		room.LastActionTime = time.Now()
-		room.UpdateTime = time.Now()
-
-		if oldStatus != room.Status {
-			// 状态改变，删除旧文档并创建新文档
-			partitionKeyOld := azcosmos.NewPartitionKeyString(oldStatus)
-			_, _ = container.DeleteItem(ctx, partitionKeyOld, room.ID, nil)
-
-			roomJSON, _ := json.Marshal(room)
-			partitionKeyNew := azcosmos.NewPartitionKeyString(room.Status)
-			_, _ = container.CreateItem(ctx, partitionKeyNew, roomJSON, nil)
-		} else {
-			// 状态未变，直接替换
-			roomJSON, _ := json.Marshal(room)
-			partitionKey := azcosmos.NewPartitionKeyString(room.Status)
-			_, _ = container.ReplaceItem(ctx, partitionKey, room.ID, roomJSON, nil)
-		}
+	room.LastActionTime = time.Now()
+	room.UpdateTime = time.Now()
 
-		// 通知更新
-		_ = config.SendToRoom(ctx, room.ID, types.PubSubMessage{
-			Type: "room_update",
-			Data: room,
-		})
+	if err := roomStore.ReplaceRoom(ctx, room); err != nil {
+		return nil, nil, false, false, err
 	}
 
-	return nil
+	return room, finished, true, false, nil
 }
 
-// CheckInactiveRooms 清理不活跃房间
-func CheckInactiveRooms(ctx context.Context) error {
-	container := config.GetContainer()
-	tenMinutesAgo := time.Now().Add(-10 * time.Minute).Format(time.RFC3339)
-
-	// Query across all partitions (waiting, playing, finished)
-	statuses := []string{"waiting", "playing", "finished"}
-	var inactiveRooms []types.GameRoom
-
-	for _, status := range statuses {
-		query := "SELECT * FROM c WHERE c.lastActionTime < @threshold"
-		partitionKey := azcosmos.NewPartitionKeyString(status)
-		queryPager := container.NewQueryItemsPager(query, partitionKey, &azcosmos.QueryOptions{
-			QueryParameters: []azcosmos.QueryParameter{
-				{Name: "@threshold", Value: tenMinutesAgo},
-			},
-		})
+// snapshotRoom 复制房间，之后移除玩家、重置棋盘和比赛比分不会影响副本
+func snapshotRoom(room *types.GameRoom) *types.GameRoom {
+	snapshot := *room
+	snapshot.Players = append([]types.Player(nil), room.Players...)
+	if room.Match != nil {
+		match := *room.Match
+		snapshot.Match = &match
+	}
+	return &snapshot
+}
 
-		for queryPager.More() {
-			response, err := queryPager.NextPage(ctx)
-			if err != nil {
-				log.Printf("Failed to query inactive rooms for status %s: %v", status, err)
-				continue
-			}
+// CheckInactiveRooms 清理不活跃房间，并关闭空闲的外部引擎进程
+func CheckInactiveRooms(ctx context.Context) error {
+	tenMinutesAgo := time.Now().Add(-10 * time.Minute)
 
-			for _, item := range response.Items {
-				var room types.GameRoom
-				if err := json.Unmarshal(item, &room); err != nil {
-					log.Printf("Failed to unmarshal room: %v", err)
-					continue
-				}
-				inactiveRooms = append(inactiveRooms, room)
-			}
-		}
+	inactiveRooms, err := roomStore.FindInactiveRooms(ctx, tenMinutesAgo)
+	if err != nil {
+		return err
 	}
 
 	if len(inactiveRooms) > 0 {
 		log.Printf("Found %d inactive rooms to clean up.", len(inactiveRooms))
 	}
 
-	for _, room := range inactiveRooms {
+	for i := range inactiveRooms {
+		room := &inactiveRooms[i]
+
+		// 计时对局由时钟判定超时，长考不算不活跃
+		if room.Status == "playing" && room.Clock != nil {
+			continue
+		}
+
 		log.Printf("Cleaning up inactive room: %s", room.ID)
 
+		// 长时间无人操作的对局判轮到行动的一方放弃，先完成赛后处理（归档、比赛结果）再删除
+		abandoned := room.Status == "playing" || room.Status == "opening"
+		if abandoned {
+			abandonIdleGame(room)
+			if err := roomStore.ReplaceRoom(ctx, room); err != nil {
+				log.Printf("Skipping inactive room %s: %v", room.ID, err)
+				continue
+			}
+			gameFinished(ctx, room)
+		}
+
+		// 删除成功后再通知，房间被并发修改时留到下一次检查
+		if err := roomStore.DeleteRoom(ctx, room); err != nil {
+			if errors.Is(err, store.ErrConflict) {
+				log.Printf("Inactive room %s was modified concurrently, skipping", room.ID)
+			} else {
+				log.Printf("Error deleting inactive room %s: %v", room.ID, err)
+			}
+			continue
+		}
+		cancelRoomTimer(room.ID)
+		closeRoomEngine(room.ID)
+
 		// 通知房间内所有用户
-		_ = config.SendToRoom(ctx, room.ID, types.PubSubMessage{
+		if abandoned {
+			_ = broadcaster.SendToRoom(ctx, room.ID, types.PubSubMessage{
+				Type: "game_update",
+				Data: room,
+			})
+		}
+		_ = broadcaster.SendToRoom(ctx, room.ID, types.PubSubMessage{
 			Type: "room_deleted",
 			Data: map[string]interface{}{
 				"roomId": room.ID,
 				"reason": "inactivity",
 			},
 		})
-
-		// 删除房间
-		partitionKey := azcosmos.NewPartitionKeyString(room.Status)
-		_, _ = container.DeleteItem(ctx, partitionKey, room.ID, nil)
 	}
 
+	reapIdleEngines()
+
 	return nil
 }
+
+// abandonIdleGame 结束长时间无人操作的对局，轮到落子（开局阶段为轮到摆子或选择颜色）的玩家判负
+//
+// 不更新 LastActionTime，写入后房间仍会被清理。
+func abandonIdleGame(room *types.GameRoom) {
+	loser := room.CurrentPlayer
+	if room.OpeningState != nil {
+		if p := findPlayer(room, room.OpeningState.UserID); p != nil {
+			loser = p.Color
+		}
+		room.OpeningState = nil
+	}
+	finishGame(room, opponentColor(loser), EndReasonAbandon, nil)
+}
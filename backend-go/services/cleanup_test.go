@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gomoku-backend/config"
+	"gomoku-backend/types"
+)
+
+// recordingBroadcaster 记录推送的消息
+type recordingBroadcaster struct {
+	mu       sync.Mutex
+	messages []types.PubSubMessage
+}
+
+func (b *recordingBroadcaster) GetClientAccessToken(ctx context.Context, userID string, roomID string) (*config.ClientTokenResponse, error) {
+	return &config.ClientTokenResponse{}, nil
+}
+
+func (b *recordingBroadcaster) SendToRoom(ctx context.Context, roomID string, message interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if msg, ok := message.(types.PubSubMessage); ok {
+		b.messages = append(b.messages, msg)
+	}
+	return nil
+}
+
+func (b *recordingBroadcaster) AddUserToRoom(ctx context.Context, userID string, roomID string) error {
+	return nil
+}
+
+func (b *recordingBroadcaster) RemoveUserFromRoom(ctx context.Context, userID string, roomID string) error {
+	return nil
+}
+
+// find 返回第一条指定类型的消息
+func (b *recordingBroadcaster) find(msgType string) (types.PubSubMessage, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, msg := range b.messages {
+		if msg.Type == msgType {
+			return msg, true
+		}
+	}
+	return types.PubSubMessage{}, false
+}
+
+// decidingGameRoom 三局两胜的第二局，alice 已胜一局，最后活动时间为 lastAction
+func decidingGameRoom(t *testing.T, lastAction time.Time) *recordingBroadcaster {
+	t.Helper()
+	s := useMemoryStore(t)
+	b := &recordingBroadcaster{}
+	previous := broadcaster
+	SetBroadcaster(b)
+	t.Cleanup(func() {
+		cancelRoomTimer("r1")
+		broadcaster = previous
+	})
+
+	room := &types.GameRoom{
+		ID: "r1",
+		Players: []types.Player{
+			{UserID: "alice", Nickname: "alice", Color: 1, IsReady: true},
+			{UserID: "bob", Nickname: "bob", Color: 2, IsReady: true},
+		},
+		Board:          newBoard(15),
+		CurrentPlayer:  2,
+		Status:         "playing",
+		Rules:          "freestyle",
+		Match:          &types.Match{BestOf: 3, Status: MatchPlaying, Wins: map[string]int{"alice": 1}, GamesPlayed: 1},
+		MoveHistory:    []types.Move{{Row: 7, Col: 7, Player: 1}},
+		StartTime:      &lastAction,
+		CreateTime:     lastAction,
+		LastActionTime: lastAction,
+	}
+	room.Board[7][7] = 1
+	if err := s.CreateRoom(context.Background(), room); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// checkMatchResult 检查推送了 alice 赢得比赛的 match_result
+func checkMatchResult(t *testing.T, b *recordingBroadcaster) {
+	t.Helper()
+	msg, ok := b.find("match_result")
+	if !ok {
+		t.Fatal("match_result was not sent")
+	}
+	match, _ := msg.Data.(map[string]interface{})["match"].(*types.Match)
+	if match == nil || match.Status != MatchFinished || match.WinnerID != "alice" || match.Wins["alice"] != 2 {
+		t.Errorf("match_result match = %+v, want alice winning 2-0", match)
+	}
+}
+
+func TestLeaveRoomSendsMatchResult(t *testing.T) {
+	b := decidingGameRoom(t, time.Now())
+	if err := LeaveRoom(context.Background(), types.LeaveRoomRequest{UserID: "bob", RoomID: "r1"}); err != nil {
+		t.Fatal(err)
+	}
+	checkMatchResult(t, b)
+
+	// 房间回到等待状态，比分重置
+	room, err := GetRoom(context.Background(), "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room.Status != "waiting" || room.Match.Status != MatchPlaying || len(room.Match.Wins) != 0 {
+		t.Errorf("room %s with match %+v, want a reset waiting room", room.Status, room.Match)
+	}
+}
+
+func TestCheckInactiveRoomsSendsMatchResult(t *testing.T) {
+	b := decidingGameRoom(t, time.Now().Add(-time.Hour))
+	if err := CheckInactiveRooms(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	checkMatchResult(t, b)
+
+	if _, ok := b.find("room_deleted"); !ok {
+		t.Error("room_deleted was not sent")
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gomoku-backend/store"
+	"gomoku-backend/types"
+)
+
+// 时间控制模式
+const (
+	TimeControlAbsolute = "absolute"
+	TimeControlFischer  = "fischer"
+	TimeControlByoyomi  = "byoyomi"
+)
+
+// 时间控制参数上限
+const (
+	maxInitialSeconds   = 3 * 60 * 60
+	maxIncrementSeconds = 10 * 60
+	maxByoyomiSeconds   = 10 * 60
+	maxByoyomiPeriods   = 10
+)
+
+// normalizeTimeControl 校验并规范化时间控制，nil 表示不计时
+func normalizeTimeControl(tc *types.TimeControl) (*types.TimeControl, error) {
+	if tc == nil || tc.Mode == "" {
+		return nil, nil
+	}
+
+	normalized := types.TimeControl{Mode: tc.Mode, InitialSeconds: tc.InitialSeconds}
+	if tc.InitialSeconds < 0 || tc.InitialSeconds > maxInitialSeconds {
+		return nil, fmt.Errorf("%w: initialSeconds must be between 0 and %d", ErrInvalidArgument, maxInitialSeconds)
+	}
+
+	switch tc.Mode {
+	case TimeControlAbsolute:
+		if tc.InitialSeconds == 0 {
+			return nil, fmt.Errorf("%w: initialSeconds is required for absolute time control", ErrInvalidArgument)
+		}
+	case TimeControlFischer:
+		if tc.InitialSeconds == 0 {
+			return nil, fmt.Errorf("%w: initialSeconds is required for fischer time control", ErrInvalidArgument)
+		}
+		if tc.IncrementSeconds < 0 || tc.IncrementSeconds > maxIncrementSeconds {
+			return nil, fmt.Errorf("%w: incrementSeconds must be between 0 and %d", ErrInvalidArgument, maxIncrementSeconds)
+		}
+		normalized.IncrementSeconds = tc.IncrementSeconds
+	case TimeControlByoyomi:
+		if tc.ByoyomiSeconds <= 0 || tc.ByoyomiSeconds > maxByoyomiSeconds {
+			return nil, fmt.Errorf("%w: byoyomiSeconds must be between 1 and %d", ErrInvalidArgument, maxByoyomiSeconds)
+		}
+		if tc.ByoyomiPeriods <= 0 || tc.ByoyomiPeriods > maxByoyomiPeriods {
+			return nil, fmt.Errorf("%w: byoyomiPeriods must be between 1 and %d", ErrInvalidArgument, maxByoyomiPeriods)
+		}
+		normalized.ByoyomiSeconds = tc.ByoyomiSeconds
+		normalized.ByoyomiPeriods = tc.ByoyomiPeriods
+	default:
+		return nil, fmt.Errorf("%w: unknown time control mode %q", ErrInvalidArgument, tc.Mode)
+	}
+
+	return &normalized, nil
+}
+
+// newClock 按时间控制创建对局时钟，当前执子方从 now 开始计时
+func newClock(tc *types.TimeControl, now time.Time) *types.GameClock {
+	initial := types.PlayerClock{
+		RemainingMs: int64(tc.InitialSeconds) * 1000,
+		Periods:     tc.ByoyomiPeriods,
+	}
+	return &types.GameClock{
+		Black:         initial,
+		White:         initial,
+		TurnStartTime: now,
+	}
+}
+
+// playerClock 返回指定颜色一方的时钟
+func playerClock(clock *types.GameClock, color int) *types.PlayerClock {
+	if color == 1 {
+		return &clock.Black
+	}
+	return &clock.White
+}
+
+// timeLeft 返回一方在本步可用的全部时间（基本用时加上剩余读秒）
+func timeLeft(tc *types.TimeControl, pc types.PlayerClock) time.Duration {
+	left := time.Duration(pc.RemainingMs) * time.Millisecond
+	if tc.Mode == TimeControlByoyomi {
+		left += time.Duration(pc.Periods*tc.ByoyomiSeconds) * time.Second
+	}
+	return left
+}
+
+// chargeClock 从一方时钟扣除本步用时，超时返回 false
+//
+// byoyomi 下先消耗基本用时，之后每个完整用尽的读秒周期扣除一次读秒，
+// 在读秒周期内落子则周期重置。fischer 在落子后加秒。
+func chargeClock(tc *types.TimeControl, pc *types.PlayerClock, elapsed time.Duration) bool {
+	if !spendClock(tc, pc, elapsed) {
+		return false
+	}
+	if tc.Mode == TimeControlFischer {
+		pc.RemainingMs += int64(tc.IncrementSeconds) * 1000
+	}
+	return true
+}
+
+// spendClock 与 chargeClock 相同但不加秒，用于没有落子的计时中断（如同意悔棋）
+func spendClock(tc *types.TimeControl, pc *types.PlayerClock, elapsed time.Duration) bool {
+	if elapsed >= timeLeft(tc, *pc) {
+		pc.RemainingMs = 0
+		pc.Periods = 0
+		return false
+	}
+
+	ms := elapsed.Milliseconds()
+	if ms <= pc.RemainingMs {
+		pc.RemainingMs -= ms
+	} else {
+		// 仅 byoyomi 会走到这里：基本用时耗尽，按读秒周期扣除
+		overtime := ms - pc.RemainingMs
+		pc.RemainingMs = 0
+		pc.Periods -= int(overtime / (int64(tc.ByoyomiSeconds) * 1000))
+	}
+	return true
+}
+
+// clockDeadline 返回当前一方的超时时刻
+func clockDeadline(room *types.GameRoom) time.Time {
+	pc := *playerClock(room.Clock, room.CurrentPlayer)
+	return room.Clock.TurnStartTime.Add(timeLeft(room.TimeControl, pc))
+}
+
+// flagFall 判断当前一方是否已超时，超时则结束对局并返回 true
+func flagFall(room *types.GameRoom, now time.Time) bool {
+	if room.Status != "playing" || room.Clock == nil || room.TimeControl == nil {
+		return false
+	}
+	if now.Before(clockDeadline(room)) {
+		return false
+	}
+
+	pc := playerClock(room.Clock, room.CurrentPlayer)
+	pc.RemainingMs = 0
+	pc.Periods = 0
+	finishGame(room, opponentColor(room.CurrentPlayer), EndReasonTimeout, nil)
+	room.LastActionTime = now
+	return true
+}
+
+// CheckTimeout 检查房间当前一方是否超时，超时则判负并通知房间
+func CheckTimeout(ctx context.Context, roomID string) error {
+	room, changed, err := updateRoom(ctx, roomID, func(room *types.GameRoom) (bool, error) {
+		return flagFall(room, time.Now()), nil
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrRoomNotFound) {
+			cancelRoomTimer(roomID)
+			return nil
+		}
+		return err
+	}
+
+	if changed {
+		log.Printf("Player %d ran out of time in room %s", room.CurrentPlayer, roomID)
+
+		// 通知房间内所有用户
+		_ = broadcaster.SendToRoom(ctx, roomID, types.PubSubMessage{
+			Type: "game_update",
+			Data: room,
+		})
+		gameFinished(ctx, room)
+	}
+
+	scheduleRoomTimer(room)
+	return nil
+}
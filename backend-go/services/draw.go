@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// drawOfferTTL 和棋提议的有效期
+const drawOfferTTL = 60 * time.Second
+
+// OfferDraw 提议和棋，提议在对方回应、对方落子、对局结束或过期后失效
+func OfferDraw(ctx context.Context, req types.DrawOfferRequest) (*types.GameRoom, error) {
+	room, _, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		if room.Status != "playing" {
+			return false, fmt.Errorf("%w: game is not in playing status", ErrInvalidAction)
+		}
+
+		player := findPlayer(room, req.UserID)
+		if player == nil {
+			return false, fmt.Errorf("%w: only players can offer a draw", ErrInvalidAction)
+		}
+		if againstBot(room, req.UserID) {
+			return false, fmt.Errorf("%w: the bot does not accept draws", ErrInvalidAction)
+		}
+
+		now := time.Now()
+		if offer := room.DrawOffer; offer != nil && now.Before(offer.ExpireTime) {
+			if offer.UserID == req.UserID {
+				return false, fmt.Errorf("%w: draw offer is already pending", ErrInvalidAction)
+			}
+			return false, fmt.Errorf("%w: opponent has already offered a draw, respond to it instead", ErrInvalidAction)
+		}
+
+		room.DrawOffer = &types.DrawOffer{
+			UserID:     req.UserID,
+			Color:      player.Color,
+			OfferTime:  now,
+			ExpireTime: now.Add(drawOfferTTL),
+		}
+		room.LastActionTime = now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %s offered a draw in room %s", req.UserID, req.RoomID)
+
+	// 通知房间内所有用户
+	_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+		Type: "draw_offered",
+		Data: room,
+	})
+
+	return room, nil
+}
+
+// RespondDraw 回应对方的和棋提议，同意则对局以平局结束
+func RespondDraw(ctx context.Context, req types.DrawRespondRequest) (*types.GameRoom, error) {
+	accept := *req.Accept
+	room, _, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		if room.Status != "playing" {
+			return false, fmt.Errorf("%w: game is not in playing status", ErrInvalidAction)
+		}
+		if findPlayer(room, req.UserID) == nil {
+			return false, fmt.Errorf("%w: only players can respond to a draw offer", ErrInvalidAction)
+		}
+
+		offer := room.DrawOffer
+		if offer == nil || offer.UserID == req.UserID {
+			return false, fmt.Errorf("%w: no pending draw offer from opponent", ErrInvalidAction)
+		}
+
+		now := time.Now()
+		if !now.Before(offer.ExpireTime) {
+			return false, fmt.Errorf("%w: draw offer has expired", ErrInvalidAction)
+		}
+
+		if accept {
+			finishGame(room, 0, EndReasonDraw, nil)
+		} else {
+			room.DrawOffer = nil
+		}
+		room.LastActionTime = now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if accept {
+		scheduleRoomTimer(room)
+
+		log.Printf("User %s accepted the draw offer in room %s", req.UserID, req.RoomID)
+
+		_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+			Type: "game_update",
+			Data: room,
+		})
+		gameFinished(ctx, room)
+	} else {
+		log.Printf("User %s declined the draw offer in room %s", req.UserID, req.RoomID)
+
+		_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+			Type: "draw_declined",
+			Data: room,
+		})
+	}
+
+	return room, nil
+}
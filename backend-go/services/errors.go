@@ -0,0 +1,16 @@
+package services
+
+import "errors"
+
+var (
+	// ErrRoomConflict 房间被并发修改且重试次数已用尽
+	ErrRoomConflict = errors.New("room is busy, please retry")
+	// ErrInvalidArgument 请求参数无效
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrInvalidMove 落子不合法（不是当前玩家、位置已占用、越界、禁手等）
+	ErrInvalidMove = errors.New("invalid move")
+	// ErrInvalidAction 当前状态下不允许该操作（非玩家认输、没有待回应的和棋提议等）
+	ErrInvalidAction = errors.New("action not allowed")
+	// ErrBusy 同时进行的搜索（局面分析、提示、解题）已达上限
+	ErrBusy = errors.New("too many analysis requests, please retry later")
+)
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gomoku-backend/notation"
+	"gomoku-backend/rules"
+	"gomoku-backend/types"
+)
+
+// GameExport 导出的对局文件
+type GameExport struct {
+	Content     []byte
+	ContentType string
+	Filename    string
+}
+
+// ExportGame 按 format（psq, sgf, renju）导出已结束的一局
+func ExportGame(ctx context.Context, gameID, format string) (*GameExport, error) {
+	if format == "" {
+		format = notation.SGF
+	}
+	if format != notation.PSQ && format != notation.SGF && format != notation.Renju {
+		return nil, fmt.Errorf("%w: format must be psq, sgf or renju", ErrInvalidArgument)
+	}
+
+	game, err := GetGame(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := notation.Export(gameRecord(game), format)
+	if err != nil {
+		return nil, err
+	}
+	return &GameExport{
+		Content:     content,
+		ContentType: notation.ContentType(format),
+		Filename:    fmt.Sprintf("%s.%s", gameID, notation.Extension(format)),
+	}, nil
+}
+
+// gameRecord 把归档的对局转换为记谱用的对局记录
+func gameRecord(game *types.GameRecord) *notation.Record {
+	rec := &notation.Record{
+		Event:     fmt.Sprintf("Room %d, game %d", game.RoomNumber, game.GameNumber),
+		BoardSize: game.BoardSize,
+		Rules:     game.Rules,
+		Moves:     game.MoveHistory,
+		Winner:    game.WinnerColor,
+		EndReason: game.EndReason,
+		StartTime: game.StartTime,
+		EndTime:   &game.EndTime,
+	}
+	if rec.Rules == "" {
+		rec.Rules = rules.Freestyle
+	}
+	for _, p := range game.Players {
+		if p.Color == 1 {
+			rec.Black = p.Nickname
+		} else {
+			rec.White = p.Nickname
+		}
+	}
+	return rec
+}
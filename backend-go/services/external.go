@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gomoku-backend/ai"
+	"gomoku-backend/piskvork"
+	"gomoku-backend/rules"
+	"gomoku-backend/types"
+)
+
+// DefaultEngineTurnTimeout 外部引擎每步的默认时间限制
+const DefaultEngineTurnTimeout = 5 * time.Second
+
+// engineIdleTimeout 外部引擎进程空闲多久后关闭，房间再次需要时重新启动
+const engineIdleTimeout = 10 * time.Minute
+
+// errEngineFailed 外部引擎超时、崩溃或给出非法着法，电脑一方判负
+var errEngineFailed = errors.New("external engine failed")
+
+// externalEngines 可用的外部引擎（名称 -> 命令），由部署配置，客户端只能按名称选择
+var externalEngines = map[string]ExternalEngine{}
+
+// engineTurnTimeout 外部引擎每步的时间限制
+var engineTurnTimeout = DefaultEngineTurnTimeout
+
+// ExternalEngine 外部引擎的启动命令
+type ExternalEngine struct {
+	Command string
+	Args    []string
+}
+
+// engineProcesses 各房间正在运行的引擎进程（roomID -> 进程）
+//
+// 每个人机房间独占一个进程，每步都用 BOARD 发送完整局面，悔棋和换局后无需额外同步。
+var engineProcesses = struct {
+	sync.Mutex
+	procs map[string]*engineProcess
+}{procs: make(map[string]*engineProcess)}
+
+// engineProcess 房间的引擎进程及其启动参数
+type engineProcess struct {
+	engine   *piskvork.Engine
+	name     string
+	size     int
+	rules    string
+	lastUsed time.Time
+}
+
+// SetExternalEngines 设置可用的外部引擎和每步的时间限制
+func SetExternalEngines(engines map[string]ExternalEngine, turnTimeout time.Duration) {
+	externalEngines = engines
+	if turnTimeout > 0 {
+		engineTurnTimeout = turnTimeout
+	}
+}
+
+// ParseExternalEngines 解析 "名称=命令 参数...,名称=命令" 格式的外部引擎配置
+func ParseExternalEngines(spec string) (map[string]ExternalEngine, error) {
+	engines := make(map[string]ExternalEngine)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, command, ok := strings.Cut(item, "=")
+		fields := strings.Fields(command)
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || len(fields) == 0 {
+			return nil, fmt.Errorf("invalid engine %q, expected name=command", item)
+		}
+		if name == BotUserID {
+			return nil, fmt.Errorf("engine name %q is reserved", name)
+		}
+		engines[name] = ExternalEngine{Command: fields[0], Args: fields[1:]}
+	}
+	return engines, nil
+}
+
+// ExternalEngineNames 返回可用的外部引擎名称
+func ExternalEngineNames() []string {
+	names := make([]string, 0, len(externalEngines))
+	for name := range externalEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// engineRule 返回规则对应的 Piskvork INFO rule 位掩码
+func engineRule(ruleName string) int {
+	switch ruleName {
+	case rules.Standard:
+		return 1
+	case rules.Renju:
+		return 4
+	case rules.Caro:
+		return 8
+	}
+	return 0
+}
+
+// externalBotMove 让房间的外部引擎为当前一方落子
+//
+// 每步限时为配置的时间限制，计时对局中不超过剩余时间。进程意外退出时重新启动并重试一次；
+// 超时、再次失败或着法非法时返回 errEngineFailed。
+func externalBotMove(room *types.GameRoom) (ai.Move, error) {
+	timeout := engineTurnTimeout
+	if room.Clock != nil && room.TimeControl != nil {
+		if left := time.Until(clockDeadline(room)); left < timeout {
+			timeout = max(left, 100*time.Millisecond)
+		}
+	}
+
+	stones := make([]piskvork.Stone, len(room.MoveHistory))
+	for i, m := range room.MoveHistory {
+		stones[i] = piskvork.Stone{Move: piskvork.Move{Row: m.Row, Col: m.Col}, Own: m.Player == room.CurrentPlayer}
+	}
+
+	start := time.Now()
+	var move piskvork.Move
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var proc *engineProcess
+		proc, err = roomEngine(room)
+		if err != nil {
+			break
+		}
+		if room.Clock != nil {
+			_ = proc.engine.Info("time_left", time.Until(clockDeadline(room)).Milliseconds())
+		}
+		if len(stones) == 0 {
+			move, err = proc.engine.Begin(timeout)
+		} else {
+			move, err = proc.engine.Board(stones, timeout)
+		}
+		if !errors.Is(err, piskvork.ErrExited) {
+			break
+		}
+		closeRoomEngine(room.ID)
+		if attempt == 0 {
+			log.Printf("Engine %s in room %s exited, restarting", room.Bot.Engine, room.ID)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, piskvork.ErrTimeout) {
+			closeRoomEngine(room.ID)
+		}
+		return ai.Move{}, fmt.Errorf("%w: %s: %v", errEngineFailed, room.Bot.Engine, err)
+	}
+
+	if room.Board[move.Row][move.Col] != rules.Empty {
+		return ai.Move{}, fmt.Errorf("%w: %s played occupied point %d,%d", errEngineFailed, room.Bot.Engine, move.Row, move.Col)
+	}
+	// 禁手判负时交给 MakeMove 处理，拒绝禁手时视为引擎失败，否则电脑会反复尝试同一点
+	if ruleSet, err := rules.Get(room.Rules); err == nil && room.ForbiddenPolicy != rules.ForbiddenLose &&
+		ruleSet.CheckForbidden(room.Board, move.Row, move.Col, room.CurrentPlayer) != rules.NotForbidden {
+		return ai.Move{}, fmt.Errorf("%w: %s played forbidden point %d,%d", errEngineFailed, room.Bot.Engine, move.Row, move.Col)
+	}
+
+	log.Printf("Engine %s in room %s chose %d,%d (%v)", room.Bot.Engine, room.ID, move.Row, move.Col, time.Since(start))
+	return ai.Move{Row: move.Row, Col: move.Col}, nil
+}
+
+// roomEngine 返回房间正在运行的引擎进程，没有、已退出或参数变化时启动新进程
+func roomEngine(room *types.GameRoom) (*engineProcess, error) {
+	size := len(room.Board)
+	engineProcesses.Lock()
+	proc := engineProcesses.procs[room.ID]
+	if proc != nil && proc.engine.Alive() && proc.name == room.Bot.Engine && proc.size == size && proc.rules == room.Rules {
+		proc.lastUsed = time.Now()
+		engineProcesses.Unlock()
+		return proc, nil
+	}
+	engineProcesses.Unlock()
+	if proc != nil {
+		closeRoomEngine(room.ID)
+	}
+
+	ext, ok := externalEngines[room.Bot.Engine]
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %q", room.Bot.Engine)
+	}
+	engine, err := piskvork.Start(piskvork.Config{
+		Command:     ext.Command,
+		Args:        ext.Args,
+		BoardSize:   size,
+		Rule:        engineRule(room.Rules),
+		TurnTimeout: engineTurnTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Started engine %s for room %s", room.Bot.Engine, room.ID)
+
+	proc = &engineProcess{engine: engine, name: room.Bot.Engine, size: size, rules: room.Rules, lastUsed: time.Now()}
+	engineProcesses.Lock()
+	engineProcesses.procs[room.ID] = proc
+	engineProcesses.Unlock()
+	return proc, nil
+}
+
+// closeRoomEngine 关闭房间的引擎进程
+func closeRoomEngine(roomID string) {
+	engineProcesses.Lock()
+	proc, ok := engineProcesses.procs[roomID]
+	delete(engineProcesses.procs, roomID)
+	engineProcesses.Unlock()
+
+	if ok {
+		go proc.engine.Close()
+	}
+}
+
+// reapIdleEngines 关闭长时间未使用的引擎进程
+func reapIdleEngines() {
+	engineProcesses.Lock()
+	var idle []string
+	for roomID, proc := range engineProcesses.procs {
+		if time.Since(proc.lastUsed) > engineIdleTimeout || !proc.engine.Alive() {
+			idle = append(idle, roomID)
+		}
+	}
+	engineProcesses.Unlock()
+
+	for _, roomID := range idle {
+		log.Printf("Closing idle engine of room %s", roomID)
+		closeRoomEngine(roomID)
+	}
+}
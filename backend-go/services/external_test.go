@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gomoku-backend/rules"
+	"gomoku-backend/types"
+)
+
+// engineRoom 使用 testdata 中的假引擎执白的人机房间，黑方已落三子
+func engineRoom(t *testing.T) *types.GameRoom {
+	t.Helper()
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("fake engine needs bash")
+	}
+
+	engines, timeout := externalEngines, engineTurnTimeout
+	SetExternalEngines(map[string]ExternalEngine{"fake": {Command: "../piskvork/testdata/fake-engine.sh"}}, 200*time.Millisecond)
+	t.Cleanup(func() { externalEngines, engineTurnTimeout = engines, timeout })
+	t.Setenv("FAKE_ENGINE_DELAY", "5")
+
+	room := &types.GameRoom{
+		ID:            "engine-test-" + t.Name(),
+		Rules:         rules.Freestyle,
+		Board:         make([][]int, 15),
+		CurrentPlayer: rules.White,
+		Bot:           &types.BotConfig{Engine: "fake", Color: rules.White},
+	}
+	for i := range room.Board {
+		room.Board[i] = make([]int, 15)
+	}
+	for i, m := range []types.Move{{Row: 7, Col: 7}, {Row: 7, Col: 8}, {Row: 8, Col: 8}} {
+		m.Player = rules.Black
+		if i%2 == 1 {
+			m.Player = rules.White
+		}
+		room.MoveHistory = append(room.MoveHistory, m)
+		room.Board[m.Row][m.Col] = m.Player
+	}
+	t.Cleanup(func() { closeRoomEngine(room.ID) })
+	return room
+}
+
+func TestExternalBotMoveRestarts(t *testing.T) {
+	for _, mode := range []string{"slow", "crash", "garbage", "occupied", "error"} {
+		t.Run(mode, func(t *testing.T) {
+			room := engineRoom(t)
+
+			t.Setenv("FAKE_ENGINE_MODE", mode)
+			if _, err := externalBotMove(room); !errors.Is(err, errEngineFailed) {
+				t.Fatalf("err = %v, want errEngineFailed", err)
+			}
+
+			// 超时、崩溃和启动失败的进程不再保留，回复非法着法的进程仍可使用，这里关闭后由下一步重新启动
+			engineProcesses.Lock()
+			_, kept := engineProcesses.procs[room.ID]
+			engineProcesses.Unlock()
+			if wantKept := mode == "garbage" || mode == "occupied"; kept != wantKept {
+				t.Errorf("engine kept = %v, want %v", kept, wantKept)
+			}
+			closeRoomEngine(room.ID)
+
+			t.Setenv("FAKE_ENGINE_MODE", "")
+			m, err := externalBotMove(room)
+			if err != nil {
+				t.Fatalf("after restart: %v", err)
+			}
+			if room.Board[m.Row][m.Col] != rules.Empty {
+				t.Fatalf("after restart: engine played occupied point %d,%d", m.Row, m.Col)
+			}
+		})
+	}
+}
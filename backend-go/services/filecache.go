@@ -0,0 +1,76 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+)
+
+// imageCache 渲染好的对局图片和动画，归档的对局不会修改，缓存不需要失效
+var imageCache = newFileCache(64 << 20)
+
+// SetImageCacheSize 设置图片缓存的总字节数，0 表示不缓存
+func SetImageCacheSize(maxBytes int) {
+	imageCache = newFileCache(maxBytes)
+}
+
+// fileCache 按总字节数淘汰最久未使用条目的文件缓存
+type fileCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	bytes    int
+	order    *list.List // 最近使用的在前
+	items    map[string]*list.Element
+}
+
+// fileCacheEntry 缓存条目
+type fileCacheEntry struct {
+	key  string
+	file *GameExport
+}
+
+func newFileCache(maxBytes int) *fileCache {
+	return &fileCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get 获取缓存的文件
+func (c *fileCache) get(key string) (*GameExport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*fileCacheEntry).file, true
+}
+
+// put 缓存文件，超过总字节数时淘汰最久未使用的条目，单个文件超过总字节数时不缓存
+func (c *fileCache) put(key string, file *GameExport) {
+	size := len(file.Content)
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.bytes -= len(elem.Value.(*fileCacheEntry).file.Content)
+		c.order.Remove(elem)
+	}
+	c.items[key] = c.order.PushFront(&fileCacheEntry{key: key, file: file})
+	c.bytes += size
+
+	for c.bytes > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*fileCacheEntry)
+		c.order.Remove(oldest)
+		delete(c.items, entry.key)
+		c.bytes -= len(entry.file.Content)
+	}
+}
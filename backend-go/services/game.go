@@ -2,15 +2,22 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
-	"gomoku-backend/config"
+	"gomoku-backend/rules"
 	"gomoku-backend/types"
+)
 
-	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+// 对局结束原因
+const (
+	EndReasonFive      = "five"
+	EndReasonDraw      = "draw"
+	EndReasonForbidden = "forbidden-move"
+	EndReasonTimeout   = "timeout"
+	EndReasonResign    = "resign"
+	EndReasonAbandon   = "abandon"
 )
 
 // JoinRoom 加入房间
@@ -25,82 +32,56 @@ func JoinRoom(ctx context.Context, req types.JoinRoomRequest) (*types.GameRoom,
 		})
 	}
 
-	room, err := GetRoom(ctx, req.RoomID)
-	if err != nil {
-		return nil, err
-	}
-
-	// 检查用户是否已在房间中
-	alreadyInRoom := false
-	for _, p := range room.Players {
-		if p.UserID == req.UserID {
-			alreadyInRoom = true
-			break
+	room, changed, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		// 检查用户是否已在房间中
+		for _, p := range room.Players {
+			if p.UserID == req.UserID {
+				return false, nil
+			}
 		}
-	}
-	if !alreadyInRoom {
 		for _, s := range room.Spectators {
 			if s.UserID == req.UserID {
-				alreadyInRoom = true
-				break
+				return false, nil
 			}
 		}
-	}
-
-	if alreadyInRoom {
-		return room, nil
-	}
-
-	oldStatus := room.Status
-
-	if len(room.Players) >= 2 {
-		// 加入为旁观者
-		room.Spectators = append(room.Spectators, types.Spectator{
-			UserID:   req.UserID,
-			Nickname: req.Nickname,
-			JoinTime: time.Now(),
-		})
-	} else {
-		// 加入为玩家
-		room.Players = append(room.Players, types.Player{
-			UserID:   req.UserID,
-			Nickname: req.Nickname,
-			Color:    2,
-			IsReady:  true,
-		})
 
-		// 两个玩家都加入后开始游戏
-		if len(room.Players) == 2 {
-			room.Status = "playing"
+		if len(room.Players) >= 2 || room.Status == "review" {
+			// 加入为旁观者，复盘房间只有创建者一个玩家
+			room.Spectators = append(room.Spectators, types.Spectator{
+				UserID:   req.UserID,
+				Nickname: req.Nickname,
+				JoinTime: time.Now(),
+			})
+		} else {
+			// 加入为玩家
+			room.Players = append(room.Players, types.Player{
+				UserID:   req.UserID,
+				Nickname: req.Nickname,
+				Color:    2,
+				IsReady:  true,
+			})
+
+			// 两个玩家都加入后开始游戏
+			if len(room.Players) == 2 {
+				startGame(room, time.Now())
+			}
 		}
-	}
-
-	room.UpdateTime = time.Now()
-	room.LastActionTime = time.Now()
 
-	// 更新数据库
-	container := config.GetContainer()
-	if oldStatus != room.Status {
-		// 状态改变，删除旧文档并创建新文档
-		partitionKeyOld := azcosmos.NewPartitionKeyString(oldStatus)
-		_, _ = container.DeleteItem(ctx, partitionKeyOld, room.ID, nil)
-
-		roomJSON, _ := json.Marshal(room)
-		partitionKeyNew := azcosmos.NewPartitionKeyString(room.Status)
-		_, err = container.CreateItem(ctx, partitionKeyNew, roomJSON, nil)
-	} else {
-		// 状态未变，直接替换
-		roomJSON, _ := json.Marshal(room)
-		partitionKey := azcosmos.NewPartitionKeyString(room.Status)
-		_, err = container.ReplaceItem(ctx, partitionKey, room.ID, roomJSON, nil)
+		room.LastActionTime = time.Now()
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to update room: %w", err)
+	if !changed {
+		return room, nil
 	}
 
+	scheduleRoomTimer(room)
+
 	// 通知房间内所有用户
-	_ = config.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+	_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
 		Type: "room_update",
 		Data: room,
 	})
@@ -110,146 +91,263 @@ func JoinRoom(ctx context.Context, req types.JoinRoomRequest) (*types.GameRoom,
 
 // MakeMove 下棋
 func MakeMove(ctx context.Context, req types.MakeMoveRequest) (*types.GameRoom, error) {
-	room, err := GetRoom(ctx, req.RoomID)
-	if err != nil {
-		return nil, err
-	}
+	timedOut := false
+	room, _, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		opening := room.Status == "opening"
+		if room.Status != "playing" && !opening {
+			return false, fmt.Errorf("%w: game is not in playing status", ErrInvalidMove)
+		}
 
-	if room.Status != "playing" {
-		return nil, fmt.Errorf("game is not in playing status")
-	}
+		if opening {
+			// 开局阶段由指定玩家摆放双方棋子
+			if err := checkOpeningTurn(room, req.UserID); err != nil {
+				return false, err
+			}
+		} else {
+			// 验证是否是当前玩家
+			currentPlayerObj := playerByColor(room, room.CurrentPlayer)
+			if currentPlayerObj == nil || currentPlayerObj.UserID != req.UserID {
+				return false, fmt.Errorf("%w: not your turn", ErrInvalidMove)
+			}
+		}
 
-	oldStatus := room.Status
+		// 验证坐标是否在棋盘内
+		size := len(room.Board)
+		if req.Row < 0 || req.Row >= size || req.Col < 0 || req.Col >= size {
+			return false, fmt.Errorf("%w: position (%d, %d) is outside the %dx%d board", ErrInvalidMove, req.Row, req.Col, size, size)
+		}
 
-	// 验证是否是当前玩家
-	var currentPlayerObj *types.Player
-	for i := range room.Players {
-		if room.Players[i].Color == room.CurrentPlayer {
-			currentPlayerObj = &room.Players[i]
-			break
+		// 验证位置是否为空
+		if room.Board[req.Row][req.Col] != 0 {
+			return false, fmt.Errorf("%w: position already occupied", ErrInvalidMove)
 		}
-	}
 
-	if currentPlayerObj == nil || currentPlayerObj.UserID != req.UserID {
-		return nil, fmt.Errorf("not your turn")
-	}
+		// 开局摆子不判断禁手和胜负，也不计时
+		if opening {
+			placeOpeningStone(room, req.Row, req.Col)
+			room.LastActionTime = time.Now()
+			return true, nil
+		}
 
-	// 验证位置是否为空
-	if room.Board[req.Row][req.Col] != 0 {
-		return nil, fmt.Errorf("position already occupied")
-	}
+		ruleSet, err := rules.Get(room.Rules)
+		if err != nil {
+			return false, err
+		}
 
-	// 放置棋子
-	room.Board[req.Row][req.Col] = room.CurrentPlayer
-	room.MoveHistory = append(room.MoveHistory, types.Move{
-		Row:    req.Row,
-		Col:    req.Col,
-		Player: room.CurrentPlayer,
-	})
+		// 检查禁手（目前仅连珠规则下的黑方）
+		forbidden := ruleSet.CheckForbidden(room.Board, req.Row, req.Col, room.CurrentPlayer)
+		if forbidden != rules.NotForbidden && room.ForbiddenPolicy != rules.ForbiddenLose {
+			return false, fmt.Errorf("%w: forbidden move: %s", ErrInvalidMove, forbidden)
+		}
 
-	// 检查是否获胜
-	hasWon := checkWin(room.Board, req.Row, req.Col)
-	isDraw := !hasWon && checkDraw(room.Board)
+		// 对方落子视为拒绝和棋提议；任何落子都使悔棋请求失效
+		if room.DrawOffer != nil && room.DrawOffer.UserID != req.UserID {
+			room.DrawOffer = nil
+		}
+		room.TakebackOffer = nil
+
+		// 按服务器时间扣除本步用时，已超时则判负且不落子
+		now := time.Now()
+		timedOut = false
+		if room.Clock != nil && room.TimeControl != nil {
+			pc := playerClock(room.Clock, room.CurrentPlayer)
+			if !chargeClock(room.TimeControl, pc, now.Sub(room.Clock.TurnStartTime)) {
+				timedOut = true
+				finishGame(room, opponentColor(room.CurrentPlayer), EndReasonTimeout, nil)
+				room.LastActionTime = now
+				return true, nil
+			}
+			room.Clock.TurnStartTime = now
+		}
 
-	if hasWon {
-		room.Status = "finished"
-		winner := currentPlayerObj.Nickname
-		room.Winner = &winner
-	} else if isDraw {
-		room.Status = "finished"
-		draw := "平局"
-		room.Winner = &draw
-	} else {
-		// 切换玩家
-		if room.CurrentPlayer == 1 {
-			room.CurrentPlayer = 2
+		// 放置棋子
+		room.Board[req.Row][req.Col] = room.CurrentPlayer
+		room.MoveHistory = append(room.MoveHistory, types.Move{
+			Row:    req.Row,
+			Col:    req.Col,
+			Player: room.CurrentPlayer,
+			Time:   &now,
+		})
+
+		// 检查是否获胜
+		var winLine []rules.Point
+		if forbidden == rules.NotForbidden {
+			winLine = ruleSet.WinningLine(room.Board, req.Row, req.Col)
+		}
+		hasWon := winLine != nil
+		isDraw := !hasWon && forbidden == rules.NotForbidden && checkDraw(room.Board)
+
+		if forbidden != rules.NotForbidden {
+			// 禁手判负，对方获胜
+			finishGame(room, opponentColor(room.CurrentPlayer), EndReasonForbidden, nil)
+		} else if hasWon {
+			finishGame(room, room.CurrentPlayer, EndReasonFive, winLine)
+		} else if isDraw {
+			finishGame(room, 0, EndReasonDraw, nil)
 		} else {
-			room.CurrentPlayer = 1
+			// 切换玩家
+			room.CurrentPlayer = opponentColor(room.CurrentPlayer)
 		}
-	}
 
-	room.LastActionTime = time.Now()
-	room.UpdateTime = time.Now()
+		room.LastActionTime = now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// 更新数据库
-	container := config.GetContainer()
-	if oldStatus != room.Status {
-		// 状态改变，删除旧文档并创建新文档
-		partitionKeyOld := azcosmos.NewPartitionKeyString(oldStatus)
-		_, _ = container.DeleteItem(ctx, partitionKeyOld, room.ID, nil)
+	scheduleRoomTimer(room)
 
-		roomJSON, _ := json.Marshal(room)
-		partitionKeyNew := azcosmos.NewPartitionKeyString(room.Status)
-		_, err = container.CreateItem(ctx, partitionKeyNew, roomJSON, nil)
+	if timedOut {
+		// 已超时的落子不生效，返回以 timeout 结束的房间
+		log.Printf("Player %d ran out of time in room %s", room.CurrentPlayer, req.RoomID)
 	} else {
-		// 状态未变，直接替换
-		roomJSON, _ := json.Marshal(room)
-		partitionKey := azcosmos.NewPartitionKeyString(room.Status)
-		_, err = container.ReplaceItem(ctx, partitionKey, room.ID, roomJSON, nil)
+		log.Printf("Sending game update to room %s for move at %d,%d", req.RoomID, req.Row, req.Col)
 	}
 
+	// 通知房间内所有用户
+	_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+		Type: "game_update",
+		Data: room,
+	})
+	gameFinished(ctx, room)
+
+	return room, nil
+}
+
+// Resign 认输，对方获胜
+func Resign(ctx context.Context, req types.ResignRequest) (*types.GameRoom, error) {
+	room, _, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		if room.Status != "playing" && room.Status != "opening" {
+			return false, fmt.Errorf("%w: game is not in playing status", ErrInvalidAction)
+		}
+
+		player := findPlayer(room, req.UserID)
+		if player == nil {
+			return false, fmt.Errorf("%w: only players can resign", ErrInvalidAction)
+		}
+
+		room.OpeningState = nil
+		finishGame(room, opponentColor(player.Color), EndReasonResign, nil)
+		room.LastActionTime = time.Now()
+		return true, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update room: %w", err)
+		return nil, err
 	}
 
-	log.Printf("Sending game update to room %s for move at %d,%d", req.RoomID, req.Row, req.Col)
+	scheduleRoomTimer(room)
+
+	log.Printf("User %s resigned in room %s", req.UserID, req.RoomID)
 
 	// 通知房间内所有用户
-	_ = config.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+	_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
 		Type: "game_update",
 		Data: room,
 	})
+	gameFinished(ctx, room)
 
 	return room, nil
 }
 
-// checkWin 检查获胜
-func checkWin(board [][]int, row, col int) bool {
-	player := board[row][col]
-	directions := [][][]int{
-		{{0, 1}, {0, -1}},  // 水平
-		{{1, 0}, {-1, 0}},  // 垂直
-		{{1, 1}, {-1, -1}}, // 主对角线
-		{{1, -1}, {-1, 1}}, // 副对角线
+// startGame 开始新的一局，棋盘和双方颜色需已就绪；设置了开局规则时先进入开局阶段
+func startGame(room *types.GameRoom, now time.Time) {
+	room.StartTime = &now
+	room.OpeningMoves = 0
+	if room.Opening != "" {
+		startOpening(room)
+		return
 	}
 
-	for _, dirs := range directions {
-		count := 1
-
-		// 检查第一个方向
-		for i := 1; i < 5; i++ {
-			newRow := row + dirs[0][0]*i
-			newCol := col + dirs[0][1]*i
-			if newRow >= 0 && newRow < 15 && newCol >= 0 && newCol < 15 && board[newRow][newCol] == player {
-				count++
-			} else {
-				break
-			}
-		}
+	room.Status = "playing"
+	if room.TimeControl != nil {
+		room.Clock = newClock(room.TimeControl, now)
+	}
+}
 
-		// 检查第二个方向
-		for i := 1; i < 5; i++ {
-			newRow := row + dirs[1][0]*i
-			newCol := col + dirs[1][1]*i
-			if newRow >= 0 && newRow < 15 && newCol >= 0 && newCol < 15 && board[newRow][newCol] == player {
-				count++
-			} else {
-				break
-			}
-		}
+// finishGame 结束对局，记入房间的对局历史并更新比赛比分，winnerColor 为 0 表示平局，winLine 为五连获胜时获胜的连子
+func finishGame(room *types.GameRoom, winnerColor int, reason string, winLine []rules.Point) {
+	room.Status = "finished"
+	room.DrawOffer = nil
+	room.TakebackOffer = nil
 
-		if count >= 5 {
-			return true
+	result := &types.GameResult{
+		WinnerColor: winnerColor,
+		Reason:      reason,
+		WinLine:     resultPoints(winLine),
+	}
+	if winner := playerByColor(room, winnerColor); winnerColor != 0 && winner != nil {
+		result.WinnerID = winner.UserID
+	}
+	setResult(room, result)
+
+	summary := recordGame(room)
+	updateMatch(room, summary.WinnerID, summary.EndTime)
+}
+
+// setResult 设置当前一局的结果，已废弃的 Winner 和 EndReason 由结果得出；result 为 nil 时一并清除
+func setResult(room *types.GameRoom, result *types.GameResult) {
+	room.Result = result
+	room.Winner = nil
+	room.EndReason = ""
+	if result == nil {
+		return
+	}
+
+	room.EndReason = result.Reason
+	if result.WinnerColor == 0 {
+		draw := "平局"
+		room.Winner = &draw
+	} else if winner := playerByColor(room, result.WinnerColor); winner != nil {
+		nickname := winner.Nickname
+		room.Winner = &nickname
+	}
+}
+
+// resultPoints 把规则包返回的连子转换为结果中的坐标
+func resultPoints(line []rules.Point) []types.Point {
+	if line == nil {
+		return nil
+	}
+	points := make([]types.Point, len(line))
+	for i, p := range line {
+		points[i] = types.Point(p)
+	}
+	return points
+}
+
+// gameFinished 对局刚结束后的后续处理：归档对局、推送比赛结果并安排赛后分析，需在房间更新成功后调用
+func gameFinished(ctx context.Context, room *types.GameRoom) {
+	if room.Status != "finished" {
+		return
+	}
+	archiveGame(ctx, room)
+	notifyMatchResult(ctx, room)
+	scheduleGameAnalysis(ctx, room)
+}
+
+// findPlayer 查找房间中的玩家，不是玩家时返回 nil
+func findPlayer(room *types.GameRoom, userID string) *types.Player {
+	for i := range room.Players {
+		if room.Players[i].UserID == userID {
+			return &room.Players[i]
 		}
 	}
+	return nil
+}
 
-	return false
+// opponentColor 返回对方颜色
+func opponentColor(color int) int {
+	if color == 1 {
+		return 2
+	}
+	return 1
 }
 
 // checkDraw 检查平局
 func checkDraw(board [][]int) bool {
-	for i := 0; i < 15; i++ {
-		for j := 0; j < 15; j++ {
+	for i := range board {
+		for j := range board[i] {
 			if board[i][j] == 0 {
 				return false
 			}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gomoku-backend/render"
+	"gomoku-backend/rules"
+	"gomoku-backend/types"
+)
+
+// 棋盘图片格式
+const (
+	ImagePNG = "png"
+	ImageSVG = "svg"
+)
+
+// 回放动画的限制
+const (
+	defaultReplayDelay    = 800 * time.Millisecond
+	minReplayDelay        = 100 * time.Millisecond
+	maxReplayDelay        = 5 * time.Second
+	replayFinalDelay      = 3 * time.Second // 最后一帧的停留时间
+	defaultReplayCellSize = 24
+	maxReplayCellSize     = 40
+	maxReplayBytes        = 8 << 20
+)
+
+// RenderGameImage 把归档对局第 move 步之后的局面绘制为图片（png 或 svg），move 为空时为终局
+//
+// 终局且以五连获胜时标出获胜的连子。
+func RenderGameImage(ctx context.Context, gameID, format string, move *int, cellSize int, moveNumbers bool) (*GameExport, error) {
+	if format != ImagePNG && format != ImageSVG {
+		return nil, fmt.Errorf("%w: image format must be png or svg", ErrInvalidArgument)
+	}
+	if cellSize == 0 {
+		cellSize = render.DefaultCellSize
+	}
+	if cellSize < render.MinCellSize || cellSize > render.MaxCellSize {
+		return nil, fmt.Errorf("%w: cell must be between %d and %d", ErrInvalidArgument, render.MinCellSize, render.MaxCellSize)
+	}
+
+	key := fmt.Sprintf("%s/image.%s?cell=%d&numbers=%t", gameID, format, cellSize, moveNumbers)
+	if move != nil {
+		key += fmt.Sprintf("&move=%d", *move)
+	}
+	if file, ok := imageCache.get(key); ok {
+		return file, nil
+	}
+
+	game, err := GetGame(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(game.MoveHistory)
+	n := total
+	if move != nil {
+		n = *move
+	}
+	if n < 0 || n > total {
+		return nil, fmt.Errorf("%w: move must be between 0 and %d", ErrInvalidArgument, total)
+	}
+
+	opts := render.Options{
+		BoardSize:   game.BoardSize,
+		Moves:       game.MoveHistory[:n],
+		CellSize:    cellSize,
+		MoveNumbers: moveNumbers,
+	}
+	if n == total {
+		opts.WinLine = gameWinLine(game)
+	}
+
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if format == ImageSVG {
+		err, contentType = render.SVG(&buf, opts), "image/svg+xml"
+	} else {
+		err = render.PNG(&buf, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file := &GameExport{
+		Content:     buf.Bytes(),
+		ContentType: contentType,
+		Filename:    fmt.Sprintf("%s-%d.%s", gameID, n, format),
+	}
+	imageCache.put(key, file)
+	return file, nil
+}
+
+// RenderGameReplay 把归档对局绘制为逐步落子的 GIF 动画，delay 为每步的间隔，为 0 时使用默认值
+//
+// 最后一帧停留 3 秒并标出获胜的连子。生成的文件超过 8MB 时返回错误，需减小格子边长。
+func RenderGameReplay(ctx context.Context, gameID string, delay time.Duration, cellSize int, moveNumbers bool) (*GameExport, error) {
+	if delay == 0 {
+		delay = defaultReplayDelay
+	}
+	if delay < minReplayDelay || delay > maxReplayDelay {
+		return nil, fmt.Errorf("%w: delay must be between %d and %d ms", ErrInvalidArgument, minReplayDelay.Milliseconds(), maxReplayDelay.Milliseconds())
+	}
+	if cellSize == 0 {
+		cellSize = defaultReplayCellSize
+	}
+	if cellSize < render.MinCellSize || cellSize > maxReplayCellSize {
+		return nil, fmt.Errorf("%w: cell must be between %d and %d", ErrInvalidArgument, render.MinCellSize, maxReplayCellSize)
+	}
+
+	key := fmt.Sprintf("%s/replay.gif?delay=%d&cell=%d&numbers=%t", gameID, delay.Milliseconds(), cellSize, moveNumbers)
+	if file, ok := imageCache.get(key); ok {
+		return file, nil
+	}
+
+	game, err := GetGame(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := render.Options{
+		BoardSize:   game.BoardSize,
+		Moves:       game.MoveHistory,
+		WinLine:     gameWinLine(game),
+		CellSize:    cellSize,
+		MoveNumbers: moveNumbers,
+	}
+	start := time.Now()
+	var buf bytes.Buffer
+	if err := render.GIF(&buf, opts, centiseconds(delay), centiseconds(replayFinalDelay)); err != nil {
+		return nil, err
+	}
+	if buf.Len() > maxReplayBytes {
+		return nil, fmt.Errorf("%w: replay is larger than %d MB, use a smaller cell", ErrInvalidArgument, maxReplayBytes>>20)
+	}
+	log.Printf("Rendered replay of game %s (%d moves, %d bytes) in %v", gameID, len(game.MoveHistory), buf.Len(), time.Since(start))
+
+	file := &GameExport{
+		Content:     buf.Bytes(),
+		ContentType: "image/gif",
+		Filename:    fmt.Sprintf("%s.gif", gameID),
+	}
+	imageCache.put(key, file)
+	return file, nil
+}
+
+// centiseconds 把时长转换为 GIF 帧延迟的单位（1/100 秒）
+func centiseconds(d time.Duration) int {
+	return int(d / (10 * time.Millisecond))
+}
+
+// gameWinLine 以五连结束的对局返回获胜的连子，其他对局返回 nil
+//
+// 较早归档的记录没有保存获胜的连子，按规则从最后一步重新计算。
+func gameWinLine(game *types.GameRecord) []rules.Point {
+	if game.EndReason != EndReasonFive || len(game.MoveHistory) == 0 {
+		return nil
+	}
+	if len(game.WinLine) > 0 {
+		line := make([]rules.Point, len(game.WinLine))
+		for i, p := range game.WinLine {
+			line[i] = rules.Point(p)
+		}
+		return line
+	}
+	ruleSet, err := rules.Get(game.Rules)
+	if err != nil {
+		return nil
+	}
+	last := game.MoveHistory[len(game.MoveHistory)-1]
+	board := boardAt(game.BoardSize, game.MoveHistory, len(game.MoveHistory))
+	return ruleSet.WinningLine(board, last.Row, last.Col)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gomoku-backend/store"
+	"gomoku-backend/types"
+)
+
+// 比赛状态
+const (
+	MatchPlaying  = "playing"
+	MatchFinished = "finished"
+)
+
+// 局间间隔
+const (
+	DefaultMatchPauseSeconds = 10
+	MaxMatchPauseSeconds     = 120
+)
+
+// newMatch 校验比赛设置并创建比赛状态，nil 表示单局
+func newMatch(cfg *types.MatchConfig) (*types.Match, error) {
+	if cfg == nil || cfg.BestOf == 0 {
+		return nil, nil
+	}
+
+	switch cfg.BestOf {
+	case 3, 5, 7:
+	default:
+		return nil, fmt.Errorf("%w: bestOf must be 3, 5 or 7", ErrInvalidArgument)
+	}
+
+	pause := cfg.PauseSeconds
+	if pause == 0 {
+		pause = DefaultMatchPauseSeconds
+	}
+	if pause < 0 || pause > MaxMatchPauseSeconds {
+		return nil, fmt.Errorf("%w: pauseSeconds must be between 1 and %d", ErrInvalidArgument, MaxMatchPauseSeconds)
+	}
+
+	match := &types.Match{BestOf: cfg.BestOf, PauseSeconds: pause}
+	resetMatch(match)
+	return match, nil
+}
+
+// resetMatch 清空比分，重新开始比赛
+func resetMatch(match *types.Match) {
+	match.Status = MatchPlaying
+	match.Wins = map[string]int{}
+	match.Draws = 0
+	match.GamesPlayed = 0
+	match.WinnerID = ""
+	match.NextGameTime = nil
+}
+
+// updateMatch 在一局结束后更新比分
+//
+// 一方胜局数过半，或已下满 BestOf 局时比赛结束（胜局多者获胜，相同则为平局）；
+// 否则安排 PauseSeconds 后自动开始下一局。
+func updateMatch(room *types.GameRoom, winnerID string, now time.Time) {
+	match := room.Match
+	if match == nil || match.Status != MatchPlaying {
+		return
+	}
+
+	match.GamesPlayed++
+	if winnerID == "" {
+		match.Draws++
+	} else {
+		match.Wins[winnerID]++
+	}
+
+	leader, leaderWins, tied := "", 0, false
+	for _, p := range room.Players {
+		wins := match.Wins[p.UserID]
+		switch {
+		case wins > leaderWins:
+			leader, leaderWins, tied = p.UserID, wins, false
+		case wins == leaderWins:
+			tied = true
+		}
+	}
+
+	if leaderWins > match.BestOf/2 || match.GamesPlayed >= match.BestOf {
+		match.Status = MatchFinished
+		match.NextGameTime = nil
+		if !tied {
+			match.WinnerID = leader
+		}
+		return
+	}
+
+	next := now.Add(time.Duration(match.PauseSeconds) * time.Second)
+	match.NextGameTime = &next
+}
+
+// StartNextMatchGame 局间间隔结束后交换颜色开始比赛的下一局
+func StartNextMatchGame(ctx context.Context, roomID string) error {
+	room, changed, err := updateRoom(ctx, roomID, func(room *types.GameRoom) (bool, error) {
+		match := room.Match
+		if room.Status != "finished" || match == nil || match.NextGameTime == nil {
+			return false, nil
+		}
+
+		now := time.Now()
+		if now.Before(*match.NextGameTime) || len(room.Players) < 2 {
+			return false, nil
+		}
+
+		startRematch(room, now)
+		return true, nil
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrRoomNotFound) {
+			cancelRoomTimer(roomID)
+			return nil
+		}
+		return err
+	}
+
+	if !changed {
+		scheduleRoomTimer(room)
+		return nil
+	}
+
+	notifyRematchStarted(ctx, room)
+	return nil
+}
+
+// notifyMatchResult 刚结束的一局决定了比赛结果时推送 match_result
+func notifyMatchResult(ctx context.Context, room *types.GameRoom) {
+	match := room.Match
+	if room.Status != "finished" || match == nil || match.Status != MatchFinished {
+		return
+	}
+
+	log.Printf("Match finished in room %s, winner: %q", room.ID, match.WinnerID)
+
+	_ = broadcaster.SendToRoom(ctx, room.ID, types.PubSubMessage{
+		Type: "match_result",
+		Data: map[string]interface{}{
+			"roomId":  room.ID,
+			"match":   match,
+			"players": room.Players,
+		},
+	})
+}
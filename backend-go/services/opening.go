@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// 开局规则
+const (
+	OpeningSwap  = "swap"
+	OpeningSwap2 = "swap2"
+)
+
+// 开局阶段
+const (
+	OpeningPlace3  = "place3"
+	OpeningChoose  = "choose"
+	OpeningPlace2  = "place2"
+	OpeningChoose2 = "choose2"
+)
+
+// 开局阶段的选择
+const (
+	ChoiceBlack  = "black"
+	ChoiceWhite  = "white"
+	ChoicePlace2 = "place2"
+)
+
+// validateOpening 校验开局规则
+func validateOpening(opening string) error {
+	switch opening {
+	case "", OpeningSwap, OpeningSwap2:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown opening %q", ErrInvalidArgument, opening)
+}
+
+// startOpening 进入开局阶段，由执黑座位的玩家摆前三子（黑、白、黑）
+func startOpening(room *types.GameRoom) {
+	room.Status = "opening"
+	room.OpeningState = &types.OpeningState{
+		Phase:  OpeningPlace3,
+		UserID: playerByColor(room, 1).UserID,
+	}
+}
+
+// placeOpeningStone 开局阶段摆子，颜色按黑白交替，摆满后进入选择阶段
+func placeOpeningStone(room *types.GameRoom, row, col int) {
+	now := time.Now()
+	room.Board[row][col] = room.CurrentPlayer
+	room.MoveHistory = append(room.MoveHistory, types.Move{
+		Row:    row,
+		Col:    col,
+		Player: room.CurrentPlayer,
+		Time:   &now,
+	})
+	room.CurrentPlayer = opponentColor(room.CurrentPlayer)
+
+	state := room.OpeningState
+	switch {
+	case state.Phase == OpeningPlace3 && len(room.MoveHistory) == 3:
+		// 由另一方选择颜色
+		state.Phase = OpeningChoose
+		state.UserID = otherPlayer(room, state.UserID).UserID
+	case state.Phase == OpeningPlace2 && len(room.MoveHistory) == 5:
+		// 再摆两子后由摆前三子的一方选择颜色
+		state.Phase = OpeningChoose2
+		state.UserID = otherPlayer(room, state.UserID).UserID
+	}
+}
+
+// checkOpeningTurn 校验开局阶段是否轮到该玩家摆子
+func checkOpeningTurn(room *types.GameRoom, userID string) error {
+	state := room.OpeningState
+	if state == nil || (state.Phase != OpeningPlace3 && state.Phase != OpeningPlace2) {
+		return fmt.Errorf("%w: waiting for a color choice", ErrInvalidMove)
+	}
+	if state.UserID != userID {
+		return fmt.Errorf("%w: not your turn", ErrInvalidMove)
+	}
+	return nil
+}
+
+// ChooseOpeningColor 开局阶段选择颜色，swap2 第一次选择时还可以选择再摆两子
+func ChooseOpeningColor(ctx context.Context, req types.OpeningChooseRequest) (*types.GameRoom, error) {
+	room, _, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		state := room.OpeningState
+		if room.Status != "opening" || state == nil {
+			return false, fmt.Errorf("%w: game is not in opening phase", ErrInvalidAction)
+		}
+		if state.Phase != OpeningChoose && state.Phase != OpeningChoose2 {
+			return false, fmt.Errorf("%w: opening stones are still being placed", ErrInvalidAction)
+		}
+		if state.UserID != req.UserID {
+			return false, fmt.Errorf("%w: not your turn to choose", ErrInvalidAction)
+		}
+
+		now := time.Now()
+		switch req.Choice {
+		case ChoiceBlack, ChoiceWhite:
+			color := 1
+			if req.Choice == ChoiceWhite {
+				color = 2
+			}
+			for i := range room.Players {
+				if room.Players[i].UserID == req.UserID {
+					room.Players[i].Color = color
+				} else {
+					room.Players[i].Color = opponentColor(color)
+				}
+			}
+			finishOpening(room, now)
+		case ChoicePlace2:
+			if room.Opening != OpeningSwap2 || state.Phase != OpeningChoose {
+				return false, fmt.Errorf("%w: placing two more stones is only allowed at the first swap2 choice", ErrInvalidAction)
+			}
+			state.Phase = OpeningPlace2
+		default:
+			return false, fmt.Errorf("%w: unknown choice %q", ErrInvalidArgument, req.Choice)
+		}
+
+		room.LastActionTime = now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scheduleRoomTimer(room)
+
+	log.Printf("User %s chose %s in the opening of room %s", req.UserID, req.Choice, req.RoomID)
+
+	// 通知房间内所有用户
+	_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+		Type: "game_update",
+		Data: room,
+	})
+
+	return room, nil
+}
+
+// finishOpening 颜色确定后结束开局阶段，由当前执子方（白方）继续对局并开始计时
+func finishOpening(room *types.GameRoom, now time.Time) {
+	room.OpeningState = nil
+	room.OpeningMoves = len(room.MoveHistory)
+	room.Status = "playing"
+	if room.TimeControl != nil {
+		room.Clock = newClock(room.TimeControl, now)
+	}
+}
+
+// playerByColor 返回执该颜色的玩家
+func playerByColor(room *types.GameRoom, color int) *types.Player {
+	for i := range room.Players {
+		if room.Players[i].Color == color {
+			return &room.Players[i]
+		}
+	}
+	return nil
+}
+
+// otherPlayer 返回另一位玩家
+func otherPlayer(room *types.GameRoom, userID string) *types.Player {
+	for i := range room.Players {
+		if room.Players[i].UserID != userID {
+			return &room.Players[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gomoku-backend/ai"
+	"gomoku-backend/rules"
+	"gomoku-backend/store"
+	"gomoku-backend/types"
+)
+
+// 赛后分析状态
+const (
+	AnalysisPending = "pending"
+	AnalysisRunning = "running"
+	AnalysisDone    = "done"
+	AnalysisFailed  = "failed"
+)
+
+// 赛后分析的棋步标记
+const (
+	LabelBlunder   = "blunder"
+	LabelMistake   = "mistake"
+	LabelMissedWin = "missed-win"
+)
+
+// 标记失误的评分损失阈值，评分单位见 ai.evaluate（活三约 200，冲四约 1000）
+const (
+	mistakeLoss = 300
+	blunderLoss = 1500
+)
+
+// postGameConfig 赛后分析的引擎参数，只按节点数限制搜索，同一盘棋的分析结果可复现
+//
+// 实际落子后的局面少搜一层，使两次评分都停在落子方之后的同一深度，避免奇偶层评分偏差。
+var postGameConfig = ai.Config{MaxDepth: 4, Width: 10, MaxNodes: 20000}
+
+// analysisStore 赛后分析报告存储，未设置时不做赛后分析
+var analysisStore store.AnalysisStore
+
+// analysisQueue 待分析的对局，由 StartAnalysisWorkers 创建
+var analysisQueue chan analysisJob
+
+// analysisJob 一局棋的分析任务
+type analysisJob struct {
+	analysis     *types.GameAnalysis
+	moves        []types.Move
+	openingMoves int
+}
+
+// SetAnalysisStore 设置赛后分析报告存储
+func SetAnalysisStore(s store.AnalysisStore) {
+	analysisStore = s
+}
+
+// StartAnalysisWorkers 启动 workers 个赛后分析协程，最多排队 queueSize 局
+//
+// 分析是 CPU 密集的长任务，固定数量的协程保证不会挤占处理请求的资源；队列满时新的分析直接标记为失败。
+func StartAnalysisWorkers(workers, queueSize int) {
+	analysisQueue = make(chan analysisJob, queueSize)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range analysisQueue {
+				runAnalysis(context.Background(), job)
+			}
+		}()
+	}
+}
+
+// GetGameAnalysis 获取对局的赛后分析报告
+func GetGameAnalysis(ctx context.Context, gameID string) (*types.GameAnalysis, error) {
+	if analysisStore == nil {
+		return nil, store.ErrAnalysisNotFound
+	}
+	return analysisStore.GetAnalysis(ctx, gameID)
+}
+
+// scheduleGameAnalysis 为房间刚结束的一局创建分析报告并加入分析队列，与 archiveGame 一样需在房间重置之前调用
+func scheduleGameAnalysis(ctx context.Context, room *types.GameRoom) {
+	if analysisStore == nil || analysisQueue == nil || len(room.Series) == 0 {
+		return
+	}
+
+	summary := room.Series[len(room.Series)-1]
+	now := time.Now()
+	analysis := &types.GameAnalysis{
+		ID:         summary.ID,
+		RoomID:     room.ID,
+		Status:     AnalysisPending,
+		Rules:      room.Rules,
+		BoardSize:  len(room.Board),
+		Moves:      []types.MoveAnalysis{},
+		CreateTime: now,
+		UpdateTime: now,
+	}
+
+	// 先保存再排队，避免覆盖分析协程写入的状态
+	if err := analysisStore.SaveAnalysis(ctx, analysis); err != nil {
+		log.Printf("Error saving analysis %s: %v", analysis.ID, err)
+		return
+	}
+
+	select {
+	case analysisQueue <- analysisJob{analysis: analysis, moves: room.MoveHistory, openingMoves: summary.OpeningMoves}:
+	default:
+		log.Printf("Analysis queue is full, skipping game %s in room %s", summary.ID, room.ID)
+		analysis.Status = AnalysisFailed
+		analysis.Error = "analysis queue is full"
+		saveAnalysis(ctx, analysis)
+	}
+}
+
+// runAnalysis 执行分析任务并保存结果
+func runAnalysis(ctx context.Context, job analysisJob) {
+	analysis := job.analysis
+	start := time.Now()
+
+	analysis.Status = AnalysisRunning
+	analysis.UpdateTime = start
+	saveAnalysis(ctx, analysis)
+
+	moves, err := analyzeGame(analysis.Rules, analysis.BoardSize, job.moves, job.openingMoves)
+	if err != nil {
+		analysis.Status = AnalysisFailed
+		analysis.Error = err.Error()
+		log.Printf("Error analyzing game %s: %v", analysis.ID, err)
+	} else {
+		analysis.Status = AnalysisDone
+		analysis.Moves = moves
+		analysis.Black, analysis.White = analysisStats(moves)
+		log.Printf("Analyzed game %s (%d moves) in %v", analysis.ID, len(job.moves), time.Since(start))
+	}
+
+	analysis.UpdateTime = time.Now()
+	saveAnalysis(ctx, analysis)
+}
+
+// saveAnalysis 保存分析报告，失败只记录日志
+func saveAnalysis(ctx context.Context, analysis *types.GameAnalysis) {
+	if err := analysisStore.SaveAnalysis(ctx, analysis); err != nil {
+		log.Printf("Error saving analysis %s: %v", analysis.ID, err)
+	}
+}
+
+// analyzeGame 逐步复盘，比较每一步与该局面最佳落子的评分并标记失误，开局阶段的摆子只复原不分析
+func analyzeGame(ruleName string, size int, moves []types.Move, openingMoves int) ([]types.MoveAnalysis, error) {
+	ruleSet, err := rules.Get(ruleName)
+	if err != nil {
+		return nil, err
+	}
+
+	board := newBoard(size)
+	replyConfig := postGameConfig
+	replyConfig.MaxDepth--
+
+	var result []types.MoveAnalysis
+	for i, m := range moves {
+		if m.Row < 0 || m.Row >= size || m.Col < 0 || m.Col >= size || board[m.Row][m.Col] != rules.Empty {
+			return nil, fmt.Errorf("invalid move %d at (%d, %d)", i+1, m.Row, m.Col)
+		}
+		if i < openingMoves {
+			board[m.Row][m.Col] = m.Player
+			continue
+		}
+
+		before, err := searchPostGame(ruleSet, postGameConfig, board, m.Player)
+		if err != nil {
+			return nil, err
+		}
+
+		forbidden := ruleSet.CheckForbidden(board, m.Row, m.Col, m.Player) != rules.NotForbidden
+		board[m.Row][m.Col] = m.Player
+
+		var played int
+		switch {
+		case forbidden:
+			played = -ai.WinScore
+		case ruleSet.IsWin(board, m.Row, m.Col):
+			played = ai.WinScore
+		default:
+			reply, err := searchPostGame(ruleSet, replyConfig, board, opponentColor(m.Player))
+			if err != nil {
+				return nil, err
+			}
+			if reply != nil {
+				played = -reply.Best.Score
+			}
+		}
+
+		ma := types.MoveAnalysis{MoveNumber: i + 1, Move: m, PlayedScore: played}
+		if before != nil {
+			ma.Best = types.CandidateMove(before.Best)
+			ma.Loss = max(0, before.Best.Score-played)
+			ma.Label = moveLabel(before, played, ma.Loss)
+		}
+		result = append(result, ma)
+	}
+	return result, nil
+}
+
+// searchPostGame 搜索赛后分析的局面，没有可落子的位置时返回 nil
+func searchPostGame(ruleSet rules.RuleSet, cfg ai.Config, board [][]int, color int) (*ai.Result, error) {
+	result, err := ai.NewEngine(ruleSet, cfg).Search(board, color)
+	if errors.Is(err, ai.ErrNoMove) {
+		return nil, nil
+	}
+	return result, err
+}
+
+// moveLabel 根据落子前的最佳结果和实际落子后的评分标记失误
+func moveLabel(best *ai.Result, played, loss int) string {
+	switch {
+	case best.IsWin() && !ai.IsWinScore(played):
+		return LabelMissedWin
+	case best.IsLoss():
+		return "" // 已经必败，任何落子都不算失误
+	case ai.IsWinScore(-played):
+		return LabelBlunder
+	case loss >= blunderLoss:
+		return LabelBlunder
+	case loss >= mistakeLoss:
+		return LabelMistake
+	}
+	return ""
+}
+
+// analysisStats 按执子颜色统计失误
+func analysisStats(moves []types.MoveAnalysis) (black, white types.AnalysisStats) {
+	for _, m := range moves {
+		stats := &black
+		if m.Move.Player == 2 {
+			stats = &white
+		}
+		switch m.Label {
+		case LabelBlunder:
+			stats.Blunders++
+		case LabelMistake:
+			stats.Mistakes++
+		case LabelMissedWin:
+			stats.MissedWins++
+		}
+	}
+	return black, white
+}
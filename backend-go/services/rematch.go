@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gomoku-backend/types"
+
+	"github.com/google/uuid"
+)
+
+// rematchOfferTTL 再来一局提议的有效期
+const rematchOfferTTL = 60 * time.Second
+
+// maxSeriesGames 房间文档中最多保留的对局摘要数，更早的对局只在归档中
+const maxSeriesGames = 20
+
+// OfferRematch 对局结束后提议再来一局，对方已提议或对方是电脑时直接开始新的一局
+func OfferRematch(ctx context.Context, req types.RematchRequest) (*types.GameRoom, error) {
+	started := false
+	room, _, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		started = false
+		if err := checkRematch(room, req.UserID); err != nil {
+			return false, err
+		}
+
+		now := time.Now()
+		if againstBot(room, req.UserID) {
+			startRematch(room, now)
+			started = true
+			return true, nil
+		}
+		if offer := room.RematchOffer; offer != nil && now.Before(offer.ExpireTime) {
+			if offer.UserID == req.UserID {
+				return false, fmt.Errorf("%w: rematch offer is already pending", ErrInvalidAction)
+			}
+			startRematch(room, now)
+			started = true
+			return true, nil
+		}
+
+		room.RematchOffer = &types.RematchOffer{
+			UserID:     req.UserID,
+			OfferTime:  now,
+			ExpireTime: now.Add(rematchOfferTTL),
+		}
+		room.LastActionTime = now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if started {
+		notifyRematchStarted(ctx, room)
+		return room, nil
+	}
+
+	log.Printf("User %s offered a rematch in room %s", req.UserID, req.RoomID)
+
+	// 通知房间内所有用户
+	_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+		Type: "rematch_offered",
+		Data: room,
+	})
+
+	return room, nil
+}
+
+// RespondRematch 回应对方的再来一局提议
+func RespondRematch(ctx context.Context, req types.RematchRespondRequest) (*types.GameRoom, error) {
+	accept := *req.Accept
+	room, _, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		if err := checkRematch(room, req.UserID); err != nil {
+			return false, err
+		}
+
+		offer := room.RematchOffer
+		if offer == nil || offer.UserID == req.UserID {
+			return false, fmt.Errorf("%w: no pending rematch offer from opponent", ErrInvalidAction)
+		}
+
+		now := time.Now()
+		if !now.Before(offer.ExpireTime) {
+			return false, fmt.Errorf("%w: rematch offer has expired", ErrInvalidAction)
+		}
+
+		if accept {
+			startRematch(room, now)
+		} else {
+			room.RematchOffer = nil
+			room.LastActionTime = now
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if accept {
+		notifyRematchStarted(ctx, room)
+		return room, nil
+	}
+
+	log.Printf("User %s declined the rematch offer in room %s", req.UserID, req.RoomID)
+
+	_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+		Type: "rematch_declined",
+		Data: room,
+	})
+
+	return room, nil
+}
+
+// checkRematch 校验房间是否可以再来一局
+func checkRematch(room *types.GameRoom, userID string) error {
+	if room.Status != "finished" {
+		return fmt.Errorf("%w: game is not finished", ErrInvalidAction)
+	}
+	if findPlayer(room, userID) == nil {
+		return fmt.Errorf("%w: only players can rematch", ErrInvalidAction)
+	}
+	if len(room.Players) < 2 {
+		return fmt.Errorf("%w: opponent has left the room", ErrInvalidAction)
+	}
+	if room.Match != nil && room.Match.Status == MatchPlaying {
+		return fmt.Errorf("%w: next game of the match starts automatically", ErrInvalidAction)
+	}
+	return nil
+}
+
+// startRematch 重置棋盘并交换双方颜色后开始新的一局，旁观者保留
+//
+// 多局制比赛已结束时再来一局会开始新的比赛。
+func startRematch(room *types.GameRoom, now time.Time) {
+	if match := room.Match; match != nil {
+		if match.Status == MatchFinished {
+			resetMatch(match)
+		}
+		match.NextGameTime = nil
+	}
+
+	for i := range room.Players {
+		room.Players[i].Color = opponentColor(room.Players[i].Color)
+		room.Players[i].TakebacksUsed = 0
+	}
+
+	room.Board = newBoard(len(room.Board))
+	room.MoveHistory = []types.Move{}
+	room.CurrentPlayer = 1
+	setResult(room, nil)
+	room.RematchOffer = nil
+	room.LastActionTime = now
+	startGame(room, now)
+}
+
+// notifyRematchStarted 通知房间新的一局已开始
+func notifyRematchStarted(ctx context.Context, room *types.GameRoom) {
+	scheduleRoomTimer(room)
+
+	log.Printf("Rematch started in room %s (game %d)", room.ID, nextGameNumber(room))
+
+	_ = broadcaster.SendToRoom(ctx, room.ID, types.PubSubMessage{
+		Type: "room_update",
+		Data: room,
+	})
+}
+
+// recordGame 将刚结束的一局按 room.Result 记入房间的对局历史
+//
+// 摘要不含着法，房间文档的大小不随对局数增长；着法在房间开始下一局前仍在 room.MoveHistory 中，由归档保存。
+func recordGame(room *types.GameRoom) types.GameSummary {
+	summary := types.GameSummary{
+		ID:           uuid.New().String(),
+		GameNumber:   nextGameNumber(room),
+		Players:      append([]types.Player(nil), room.Players...),
+		WinnerID:     room.Result.WinnerID,
+		WinnerColor:  room.Result.WinnerColor,
+		EndReason:    room.Result.Reason,
+		WinLine:      room.Result.WinLine,
+		Moves:        len(room.MoveHistory),
+		OpeningMoves: room.OpeningMoves,
+		StartTime:    room.StartTime,
+		EndTime:      time.Now(),
+	}
+	room.Series = append(room.Series, summary)
+	if len(room.Series) > maxSeriesGames {
+		room.Series = append([]types.GameSummary(nil), room.Series[len(room.Series)-maxSeriesGames:]...)
+	}
+	return summary
+}
+
+// nextGameNumber 房间下一局的序号，从 1 开始
+func nextGameNumber(room *types.GameRoom) int {
+	if len(room.Series) == 0 {
+		return 1
+	}
+	return room.Series[len(room.Series)-1].GameNumber + 1
+}
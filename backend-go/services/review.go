@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"gomoku-backend/notation"
+	"gomoku-backend/rules"
+	"gomoku-backend/types"
+
+	"github.com/google/uuid"
+)
+
+// ImportGame 导入对局记录，创建棋盘和着法已摆好的复盘房间
+//
+// 着法按房间规则逐步校验：不能越界、重复落子、颠倒黑白顺序、走禁手，也不能在分出胜负后继续。
+// 复盘房间不能落子，创建者是唯一的玩家（不执子），其他人加入时都是旁观者，创建者离开后房间删除。
+// 导入的房间显示终局，房主可以用 StepReview 跳转到任意一步。
+func ImportGame(ctx context.Context, req types.ImportGameRequest) (*types.GameRoom, error) {
+	switch req.Format {
+	case "", notation.PSQ, notation.SGF, notation.Renju:
+	default:
+		return nil, fmt.Errorf("%w: format must be psq, sgf or renju", ErrInvalidArgument)
+	}
+	boardSize := req.BoardSize
+	if boardSize == 0 {
+		boardSize = DefaultBoardSize
+	}
+
+	rec, err := notation.Parse([]byte(req.Content), req.Format, boardSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	if rec.BoardSize < MinBoardSize || rec.BoardSize > MaxBoardSize {
+		return nil, fmt.Errorf("%w: boardSize must be between %d and %d", ErrInvalidArgument, MinBoardSize, MaxBoardSize)
+	}
+
+	ruleName := req.Rules
+	if ruleName == "" {
+		ruleName = strings.ToLower(rec.Rules)
+	}
+	ruleSet, err := rules.Get(ruleName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v, set rules explicitly", ErrInvalidArgument, err)
+	}
+
+	review, err := replayRecord(ruleSet, rec)
+	if err != nil {
+		return nil, err
+	}
+	return createReviewRoom(ctx, req.UserID, req.Nickname, rec.BoardSize, ruleSet.Name(), review, len(review.Moves))
+}
+
+// ReplayGame 回放归档的对局，创建从空棋盘开始的复盘房间，房主逐步前进或后退，旁观者同步看到同一局面
+func ReplayGame(ctx context.Context, req types.ReplayGameRequest) (*types.GameRoom, error) {
+	game, err := GetGame(ctx, req.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := gameRecord(game)
+	ruleSet, err := rules.Get(rec.Rules)
+	if err != nil {
+		return nil, err
+	}
+	review, err := replayRecord(ruleSet, rec)
+	if err != nil {
+		return nil, err
+	}
+	review.GameID = game.ID
+	return createReviewRoom(ctx, req.UserID, req.Nickname, game.BoardSize, ruleSet.Name(), review, 0)
+}
+
+// StepReview 复盘房间跳转到第 move 步之后的局面，只有房主可以操作
+func StepReview(ctx context.Context, req types.ReviewStepRequest) (*types.GameRoom, error) {
+	room, changed, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		if room.Status != "review" || room.Review == nil {
+			return false, fmt.Errorf("%w: room is not a review room", ErrInvalidAction)
+		}
+		if room.Creator.UserID != req.UserID {
+			return false, fmt.Errorf("%w: only the host can step through the game", ErrInvalidAction)
+		}
+		move := *req.Move
+		if move < 0 || move > len(room.Review.Moves) {
+			return false, fmt.Errorf("%w: move must be between 0 and %d", ErrInvalidArgument, len(room.Review.Moves))
+		}
+		if move == room.Review.Cursor {
+			return false, nil
+		}
+
+		setReviewCursor(room, move)
+		room.LastActionTime = time.Now()
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		// 通知房间内所有用户
+		_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+			Type: "room_update",
+			Data: room,
+		})
+	}
+	return room, nil
+}
+
+// GetGamePosition 获取归档对局第 move 步之后的局面，move 为空时返回终局
+func GetGamePosition(ctx context.Context, gameID string, move *int) (*types.GamePosition, error) {
+	game, err := GetGame(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(game.MoveHistory)
+	n := total
+	if move != nil {
+		n = *move
+	}
+	if n < 0 || n > total {
+		return nil, fmt.Errorf("%w: move must be between 0 and %d", ErrInvalidArgument, total)
+	}
+
+	position := &types.GamePosition{
+		GameID:        game.ID,
+		Move:          n,
+		TotalMoves:    total,
+		Board:         boardAt(game.BoardSize, game.MoveHistory, n),
+		CurrentPlayer: 1 + n%2,
+	}
+	if n > 0 {
+		position.LastMove = &game.MoveHistory[n-1]
+	}
+	return position, nil
+}
+
+// createReviewRoom 创建复盘房间并显示前 cursor 步，创建者是唯一的玩家
+func createReviewRoom(ctx context.Context, userID, nickname string, boardSize int, ruleName string, review *types.ReviewState, cursor int) (*types.GameRoom, error) {
+	// 检查用户是否已在其他房间
+	existingRoom, err := FindRoomByUserID(ctx, userID)
+	if err == nil && existingRoom != nil {
+		_ = LeaveRoom(ctx, types.LeaveRoomRequest{
+			UserID: userID,
+			RoomID: existingRoom.ID,
+		})
+	}
+
+	now := time.Now()
+	room := types.GameRoom{
+		ID:         uuid.New().String(),
+		RoomNumber: 1000 + rand.Intn(9000),
+		Creator: types.Creator{
+			UserID:   userID,
+			Nickname: nickname,
+		},
+		Players: []types.Player{
+			{
+				UserID:   userID,
+				Nickname: nickname,
+				IsReady:  true,
+			},
+		},
+		Spectators:     []types.Spectator{},
+		BoardSize:      boardSize,
+		Status:         "review",
+		Rules:          ruleName,
+		Review:         review,
+		CreateTime:     now,
+		UpdateTime:     now,
+		LastActionTime: now,
+	}
+	setReviewCursor(&room, cursor)
+
+	if err := roomStore.CreateRoom(ctx, &room); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// setReviewCursor 把复盘房间的棋盘和着法设为完整着法的前 n 步
+func setReviewCursor(room *types.GameRoom, n int) {
+	moves := room.Review.Moves
+	room.Review.Cursor = n
+	room.Board = boardAt(room.BoardSize, moves, n)
+	room.MoveHistory = append([]types.Move{}, moves[:n]...)
+	room.CurrentPlayer = 1 + n%2
+}
+
+// boardAt 返回摆放前 n 步之后的棋盘，着法需已校验
+func boardAt(size int, moves []types.Move, n int) [][]int {
+	board := newBoard(size)
+	for _, m := range moves[:n] {
+		board[m.Row][m.Col] = m.Player
+	}
+	return board
+}
+
+// replayRecord 按规则逐步摆放对局记录中的着法进行校验，返回复盘信息
+//
+// 因禁手判负的对局，最后一步允许是禁手。
+func replayRecord(ruleSet rules.RuleSet, rec *notation.Record) (*types.ReviewState, error) {
+	review := &types.ReviewState{
+		Event:       rec.Event,
+		Black:       rec.Black,
+		White:       rec.White,
+		WinnerColor: rec.Winner,
+		EndReason:   rec.EndReason,
+		Moves:       rec.Moves,
+	}
+	if review.Moves == nil {
+		review.Moves = []types.Move{}
+	}
+
+	board := newBoard(rec.BoardSize)
+	size := rec.BoardSize
+	for i, m := range rec.Moves {
+		n := i + 1
+		if want := 1 + i%2; m.Player != want {
+			return nil, fmt.Errorf("%w: move %d: expected %s to move", ErrInvalidArgument, n, colorName(want))
+		}
+		if m.Row < 0 || m.Row >= size || m.Col < 0 || m.Col >= size {
+			return nil, fmt.Errorf("%w: move %d: position (%d, %d) is outside the %dx%d board", ErrInvalidArgument, n, m.Row, m.Col, size, size)
+		}
+		if board[m.Row][m.Col] != rules.Empty {
+			return nil, fmt.Errorf("%w: move %d: position (%d, %d) is already occupied", ErrInvalidArgument, n, m.Row, m.Col)
+		}
+		forbidden := ruleSet.CheckForbidden(board, m.Row, m.Col, m.Player)
+		if forbidden != rules.NotForbidden && (n != len(rec.Moves) || rec.EndReason != EndReasonForbidden) {
+			return nil, fmt.Errorf("%w: move %d: forbidden move: %s", ErrInvalidArgument, n, forbidden)
+		}
+
+		board[m.Row][m.Col] = m.Player
+		if forbidden == rules.NotForbidden && ruleSet.IsWin(board, m.Row, m.Col) {
+			if n != len(rec.Moves) {
+				return nil, fmt.Errorf("%w: move %d: the game was already won by %s at move %d", ErrInvalidArgument, n+1, colorName(m.Player), n)
+			}
+			review.WinnerColor, review.EndReason = m.Player, EndReasonFive
+		}
+	}
+	return review, nil
+}
+
+// colorName 返回颜色的英文名称，用于错误信息
+func colorName(color int) string {
+	if color == 1 {
+		return "black"
+	}
+	return "white"
+}
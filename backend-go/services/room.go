@@ -2,21 +2,104 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
 	"math/rand"
 	"time"
 
-	"gomoku-backend/config"
+	"gomoku-backend/realtime"
+	"gomoku-backend/rules"
+	"gomoku-backend/store"
 	"gomoku-backend/types"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 	"github.com/google/uuid"
 )
 
+// 棋盘大小范围
+const (
+	DefaultBoardSize = 15
+	MinBoardSize     = 9
+	MaxBoardSize     = 19
+)
+
+var (
+	// roomStore 房间存储
+	roomStore store.RoomStore
+	// broadcaster 实时消息推送
+	broadcaster realtime.Broadcaster
+)
+
+// SetRoomStore 设置服务使用的房间存储
+func SetRoomStore(s store.RoomStore) {
+	roomStore = s
+}
+
+// SetBroadcaster 设置服务使用的实时消息推送
+func SetBroadcaster(b realtime.Broadcaster) {
+	broadcaster = b
+}
+
 // CreateRoom 创建房间
 func CreateRoom(ctx context.Context, req types.CreateRoomRequest) (*types.GameRoom, error) {
+	// 校验规则
+	ruleSet, err := rules.Get(req.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	forbiddenPolicy := ""
+	if ruleSet.Name() == rules.Renju {
+		switch req.ForbiddenPolicy {
+		case "", rules.ForbiddenReject:
+			forbiddenPolicy = rules.ForbiddenReject
+		case rules.ForbiddenLose:
+			forbiddenPolicy = rules.ForbiddenLose
+		default:
+			return nil, fmt.Errorf("%w: unknown forbiddenPolicy %q", ErrInvalidArgument, req.ForbiddenPolicy)
+		}
+	}
+
+	// 校验棋盘大小
+	boardSize := req.BoardSize
+	if boardSize == 0 {
+		boardSize = DefaultBoardSize
+	}
+	if boardSize < MinBoardSize || boardSize > MaxBoardSize {
+		return nil, fmt.Errorf("%w: boardSize must be between %d and %d", ErrInvalidArgument, MinBoardSize, MaxBoardSize)
+	}
+
+	timeControl, err := normalizeTimeControl(req.TimeControl)
+	if err != nil {
+		return nil, err
+	}
+
+	// 校验悔棋次数，排位对局不允许悔棋
+	takebackLimit := DefaultTakebackLimit
+	if req.TakebackLimit != nil {
+		takebackLimit = *req.TakebackLimit
+	}
+	if req.Rated {
+		if req.TakebackLimit != nil && *req.TakebackLimit > 0 {
+			return nil, fmt.Errorf("%w: takebacks are not allowed in rated games", ErrInvalidArgument)
+		}
+		takebackLimit = 0
+	}
+	if takebackLimit < 0 || takebackLimit > MaxTakebackLimit {
+		return nil, fmt.Errorf("%w: takebackLimit must be between 0 and %d", ErrInvalidArgument, MaxTakebackLimit)
+	}
+
+	match, err := newMatch(req.Match)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateOpening(req.Opening); err != nil {
+		return nil, err
+	}
+
+	bot, err := validateBot(req.Bot, req)
+	if err != nil {
+		return nil, err
+	}
+
 	// 检查用户是否已在其他房间
 	existingRoom, err := FindRoomByUserID(ctx, req.UserID)
 	if err == nil && existingRoom != nil {
@@ -27,17 +110,9 @@ func CreateRoom(ctx context.Context, req types.CreateRoomRequest) (*types.GameRo
 		})
 	}
 
-	container := config.GetContainer()
-
 	// 生成房间号
 	roomNumber := 1000 + rand.Intn(9000)
 
-	// 创建棋盘
-	board := make([][]int, 15)
-	for i := range board {
-		board[i] = make([]int, 15)
-	}
-
 	now := time.Now()
 	room := types.GameRoom{
 		ID:         uuid.New().String(),
@@ -54,137 +129,64 @@ func CreateRoom(ctx context.Context, req types.CreateRoomRequest) (*types.GameRo
 				IsReady:  true,
 			},
 		},
-		Spectators:     []types.Spectator{},
-		Board:          board,
-		CurrentPlayer:  1,
-		Status:         "waiting",
-		MoveHistory:    []types.Move{},
-		Winner:         nil,
-		CreateTime:     now,
-		UpdateTime:     now,
-		LastActionTime: now,
-	}
-
-	// 序列化为 JSON
-	roomJSON, err := json.Marshal(room)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal room: %w", err)
+		Spectators:      []types.Spectator{},
+		Board:           newBoard(boardSize),
+		BoardSize:       boardSize,
+		CurrentPlayer:   1,
+		Status:          "waiting",
+		Rules:           ruleSet.Name(),
+		ForbiddenPolicy: forbiddenPolicy,
+		TimeControl:     timeControl,
+		Rated:           req.Rated,
+		TakebackLimit:   takebackLimit,
+		Match:           match,
+		Opening:         req.Opening,
+		Bot:             bot,
+		MoveHistory:     []types.Move{},
+		Winner:          nil,
+		CreateTime:      now,
+		UpdateTime:      now,
+		LastActionTime:  now,
+	}
+
+	// 人机对局直接开始
+	if bot != nil {
+		room.Players[0].Color = opponentColor(bot.Color)
+		room.Players = append(room.Players, newBotPlayer(bot))
+		startGame(&room, now)
 	}
 
 	// 创建文档
-	partitionKey := azcosmos.NewPartitionKeyString(room.Status)
-	_, err = container.CreateItem(ctx, partitionKey, roomJSON, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create room: %w", err)
+	if err := roomStore.CreateRoom(ctx, &room); err != nil {
+		return nil, err
 	}
 
+	// 电脑执黑时由定时器触发电脑落子
+	scheduleRoomTimer(&room)
+
 	return &room, nil
 }
 
-// GetRooms 获取房间列表
-func GetRooms(ctx context.Context) ([]types.GameRoom, error) {
-	container := config.GetContainer()
-
-	// Query each partition separately
-	statuses := []string{"waiting", "playing"}
-	var rooms []types.GameRoom
-
-	for _, status := range statuses {
-		// When querying within a partition, we don't need the status filter in the query
-		query := "SELECT * FROM c ORDER BY c.createTime DESC"
-		partitionKey := azcosmos.NewPartitionKeyString(status)
-		queryPager := container.NewQueryItemsPager(query, partitionKey, nil)
-
-		for queryPager.More() {
-			response, err := queryPager.NextPage(ctx)
-			if err != nil {
-				log.Printf("Failed to query rooms for status %s: %v", status, err)
-				continue
-			}
-
-			for _, item := range response.Items {
-				var room types.GameRoom
-				if err := json.Unmarshal(item, &room); err != nil {
-					log.Printf("Failed to unmarshal room: %v", err)
-					continue
-				}
-				rooms = append(rooms, room)
-			}
-		}
+// newBoard 创建 size x size 的空棋盘
+func newBoard(size int) [][]int {
+	board := make([][]int, size)
+	for i := range board {
+		board[i] = make([]int, size)
 	}
+	return board
+}
 
-	return rooms, nil
+// GetRooms 获取房间列表
+func GetRooms(ctx context.Context) ([]types.GameRoom, error) {
+	return roomStore.ListRooms(ctx, []string{"waiting", "opening", "playing", "review"})
 }
 
 // GetRoom 获取单个房间
 func GetRoom(ctx context.Context, roomID string) (*types.GameRoom, error) {
-	container := config.GetContainer()
-
-	// Query each partition separately
-	statuses := []string{"waiting", "playing", "finished"}
-	for _, status := range statuses {
-		query := "SELECT * FROM c WHERE c.id = @id"
-		partitionKey := azcosmos.NewPartitionKeyString(status)
-		queryPager := container.NewQueryItemsPager(query, partitionKey, &azcosmos.QueryOptions{
-			QueryParameters: []azcosmos.QueryParameter{
-				{Name: "@id", Value: roomID},
-			},
-		})
-
-		for queryPager.More() {
-			response, err := queryPager.NextPage(ctx)
-			if err != nil {
-				log.Printf("Failed to query room in status %s: %v", status, err)
-				continue
-			}
-
-			if len(response.Items) > 0 {
-				var room types.GameRoom
-				if err := json.Unmarshal(response.Items[0], &room); err != nil {
-					return nil, fmt.Errorf("failed to unmarshal room: %w", err)
-				}
-				return &room, nil
-			}
-		}
-	}
-
-	return nil, fmt.Errorf("room not found")
+	return roomStore.GetRoom(ctx, roomID)
 }
 
 // FindRoomByUserID 根据用户ID查找房间
 func FindRoomByUserID(ctx context.Context, userID string) (*types.GameRoom, error) {
-	container := config.GetContainer()
-
-	// Query each partition separately
-	statuses := []string{"waiting", "playing", "finished"}
-	for _, status := range statuses {
-		query := `SELECT * FROM c 
-			WHERE EXISTS(SELECT VALUE p FROM p IN c.players WHERE p.userId = @userId) 
-			OR EXISTS(SELECT VALUE s FROM s IN c.spectators WHERE s.userId = @userId)`
-
-		partitionKey := azcosmos.NewPartitionKeyString(status)
-		queryPager := container.NewQueryItemsPager(query, partitionKey, &azcosmos.QueryOptions{
-			QueryParameters: []azcosmos.QueryParameter{
-				{Name: "@userId", Value: userID},
-			},
-		})
-
-		for queryPager.More() {
-			response, err := queryPager.NextPage(ctx)
-			if err != nil {
-				log.Printf("Failed to find room by user in status %s: %v", status, err)
-				continue
-			}
-
-			if len(response.Items) > 0 {
-				var room types.GameRoom
-				if err := json.Unmarshal(response.Items[0], &room); err != nil {
-					return nil, fmt.Errorf("failed to unmarshal room: %w", err)
-				}
-				return &room, nil
-			}
-		}
-	}
-
-	return nil, nil
+	return roomStore.FindRoomByUserID(ctx, userID)
 }
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gomoku-backend/ai"
+	"gomoku-backend/rules"
+	"gomoku-backend/types"
+)
+
+// SolvePosition 在任意局面上为进攻方搜索 VCF 或 VCT 必胜序列，不涉及房间
+func SolvePosition(ctx context.Context, req types.SolveRequest) (*types.SolveResult, error) {
+	ruleSet, err := rules.Get(req.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	mode := ai.SolveMode(req.Mode)
+	if mode == "" {
+		mode = ai.VCF
+	}
+	if mode != ai.VCF && mode != ai.VCT {
+		return nil, fmt.Errorf("%w: mode must be vcf or vct", ErrInvalidArgument)
+	}
+
+	// 节点数只能调低，避免单个请求占用过多 CPU
+	cfg := ai.DefaultSolveConfig(mode)
+	if req.MaxNodes < 0 || req.MaxNodes > cfg.MaxNodes {
+		return nil, fmt.Errorf("%w: maxNodes must be between 0 and %d", ErrInvalidArgument, cfg.MaxNodes)
+	}
+	if req.MaxNodes > 0 {
+		cfg.MaxNodes = req.MaxNodes
+	}
+
+	board, color, err := requestPosition(req.Board, req.Moves, req.BoardSize, req.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := acquireSearch()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	sol, err := ai.Solve(ruleSet, board, color, mode, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	line := pvMoves(sol.Line, color)
+	if sol.DefenderForbidden {
+		// 防守方没有合法应手，最后一手成五仍是进攻方的
+		line[len(line)-1].Player = color
+	}
+	return &types.SolveResult{
+		Color:             color,
+		Mode:              string(sol.Mode),
+		Found:             sol.Found,
+		Line:              line,
+		Nodes:             sol.Nodes,
+		Aborted:           sol.Aborted,
+		DefenderForbidden: sol.DefenderForbidden,
+	}, nil
+}
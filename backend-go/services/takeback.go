@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// 悔棋次数
+const (
+	DefaultTakebackLimit = 3
+	MaxTakebackLimit     = 10
+)
+
+// takebackOfferTTL 悔棋请求的有效期
+const takebackOfferTTL = 60 * time.Second
+
+// RequestTakeback 请求撤回自己的上一步棋
+//
+// 轮到对方时只撤回自己刚下的一步；轮到自己时连同对方的应手一起撤回两步。
+// 请求在对方回应、任何一方落子、对局结束或过期后失效。对手是电脑时直接同意。
+func RequestTakeback(ctx context.Context, req types.TakebackRequest) (*types.GameRoom, error) {
+	accepted, timedOut := false, false
+	room, _, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		accepted, timedOut = false, false
+		if room.Status != "playing" {
+			return false, fmt.Errorf("%w: game is not in playing status", ErrInvalidAction)
+		}
+		if room.Rated {
+			return false, fmt.Errorf("%w: takebacks are disabled in rated games", ErrInvalidAction)
+		}
+
+		player := findPlayer(room, req.UserID)
+		if player == nil {
+			return false, fmt.Errorf("%w: only players can request a takeback", ErrInvalidAction)
+		}
+		if player.TakebacksUsed >= room.TakebackLimit {
+			return false, fmt.Errorf("%w: no takebacks left (limit %d)", ErrInvalidAction, room.TakebackLimit)
+		}
+
+		now := time.Now()
+		if offer := room.TakebackOffer; offer != nil && now.Before(offer.ExpireTime) {
+			return false, fmt.Errorf("%w: a takeback request is already pending", ErrInvalidAction)
+		}
+
+		moveCount := takebackMoveCount(room.MoveHistory[room.OpeningMoves:], player.Color)
+		if moveCount == 0 {
+			return false, fmt.Errorf("%w: no move to take back", ErrInvalidAction)
+		}
+
+		offer := &types.TakebackOffer{
+			UserID:      req.UserID,
+			Color:       player.Color,
+			MoveCount:   moveCount,
+			RequestTime: now,
+			ExpireTime:  now.Add(takebackOfferTTL),
+		}
+		if againstBot(room, req.UserID) {
+			accepted = true
+			timedOut = !acceptTakeback(room, offer, now)
+		} else {
+			room.TakebackOffer = offer
+		}
+		room.LastActionTime = now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if accepted {
+		scheduleRoomTimer(room)
+
+		if timedOut {
+			log.Printf("Player %d ran out of time in room %s", room.CurrentPlayer, req.RoomID)
+		} else {
+			log.Printf("Bot accepted the takeback request of user %s in room %s", req.UserID, req.RoomID)
+		}
+
+		_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+			Type: "game_update",
+			Data: room,
+		})
+		gameFinished(ctx, room)
+		return room, nil
+	}
+
+	log.Printf("User %s requested a takeback in room %s", req.UserID, req.RoomID)
+
+	// 通知房间内所有用户
+	_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+		Type: "takeback_requested",
+		Data: room,
+	})
+
+	return room, nil
+}
+
+// RespondTakeback 回应对方的悔棋请求，同意则撤回棋步并轮到请求方
+func RespondTakeback(ctx context.Context, req types.TakebackRespondRequest) (*types.GameRoom, error) {
+	accept := *req.Accept
+	timedOut := false
+	room, _, err := updateRoom(ctx, req.RoomID, func(room *types.GameRoom) (bool, error) {
+		timedOut = false
+		if room.Status != "playing" {
+			return false, fmt.Errorf("%w: game is not in playing status", ErrInvalidAction)
+		}
+		if findPlayer(room, req.UserID) == nil {
+			return false, fmt.Errorf("%w: only players can respond to a takeback request", ErrInvalidAction)
+		}
+
+		offer := room.TakebackOffer
+		if offer == nil || offer.UserID == req.UserID {
+			return false, fmt.Errorf("%w: no pending takeback request from opponent", ErrInvalidAction)
+		}
+
+		now := time.Now()
+		if !now.Before(offer.ExpireTime) {
+			return false, fmt.Errorf("%w: takeback request has expired", ErrInvalidAction)
+		}
+
+		room.TakebackOffer = nil
+		if accept {
+			timedOut = !acceptTakeback(room, offer, now)
+		}
+		room.LastActionTime = now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if accept {
+		scheduleRoomTimer(room)
+
+		if timedOut {
+			// 当前一方在请求期间已超时，悔棋不生效
+			log.Printf("Player %d ran out of time in room %s", room.CurrentPlayer, req.RoomID)
+		} else {
+			log.Printf("User %s accepted the takeback request in room %s", req.UserID, req.RoomID)
+		}
+
+		_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+			Type: "game_update",
+			Data: room,
+		})
+		gameFinished(ctx, room)
+	} else {
+		log.Printf("User %s declined the takeback request in room %s", req.UserID, req.RoomID)
+
+		_ = broadcaster.SendToRoom(ctx, req.RoomID, types.PubSubMessage{
+			Type: "takeback_declined",
+			Data: room,
+		})
+	}
+
+	return room, nil
+}
+
+// acceptTakeback 撤回请求的棋步并轮到请求方
+//
+// 计时对局中先扣除当前一方从本步开始到现在的用时，已超时则判负且不撤回，返回 false。
+func acceptTakeback(room *types.GameRoom, offer *types.TakebackOffer, now time.Time) bool {
+	if room.Clock != nil && room.TimeControl != nil {
+		pc := playerClock(room.Clock, room.CurrentPlayer)
+		if !spendClock(room.TimeControl, pc, now.Sub(room.Clock.TurnStartTime)) {
+			finishGame(room, opponentColor(room.CurrentPlayer), EndReasonTimeout, nil)
+			return false
+		}
+	}
+
+	undoMoves(room, offer.MoveCount)
+	room.CurrentPlayer = offer.Color
+	if requester := findPlayer(room, offer.UserID); requester != nil {
+		requester.TakebacksUsed++
+	}
+	// 请求方重新开始计时，已用时间不退还
+	if room.Clock != nil {
+		room.Clock.TurnStartTime = now
+	}
+	return true
+}
+
+// takebackMoveCount 计算撤回 color 一方上一步需要撤回的步数，没有可撤回的棋步时返回 0
+func takebackMoveCount(history []types.Move, color int) int {
+	n := len(history)
+	switch {
+	case n >= 1 && history[n-1].Player == color:
+		return 1
+	case n >= 2 && history[n-2].Player == color:
+		return 2
+	}
+	return 0
+}
+
+// undoMoves 从棋盘和棋谱中撤回最后 count 步
+func undoMoves(room *types.GameRoom, count int) {
+	for i := 0; i < count && len(room.MoveHistory) > 0; i++ {
+		last := room.MoveHistory[len(room.MoveHistory)-1]
+		room.Board[last.Row][last.Col] = 0
+		room.MoveHistory = room.MoveHistory[:len(room.MoveHistory)-1]
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gomoku-backend/realtime"
+	"gomoku-backend/types"
+)
+
+// timedTakebackRoom 计时对局，黑方 alice 请求撤回刚下的一步，白方 bob 已思考 10 秒、剩余 remaining
+func timedTakebackRoom(t *testing.T, remaining time.Duration) {
+	t.Helper()
+	s := useMemoryStore(t)
+	previous := broadcaster
+	SetBroadcaster(realtime.NewHub("ws://localhost/ws", "test"))
+	t.Cleanup(func() {
+		cancelRoomTimer("r1")
+		broadcaster = previous
+	})
+
+	now := time.Now()
+	room := &types.GameRoom{
+		ID: "r1",
+		Players: []types.Player{
+			{UserID: "alice", Nickname: "alice", Color: 1, IsReady: true},
+			{UserID: "bob", Nickname: "bob", Color: 2, IsReady: true},
+		},
+		Board:         newBoard(15),
+		CurrentPlayer: 2,
+		Status:        "playing",
+		Rules:         "freestyle",
+		TakebackLimit: DefaultTakebackLimit,
+		TimeControl:   &types.TimeControl{Mode: TimeControlFischer, InitialSeconds: 60, IncrementSeconds: 5},
+		Clock: &types.GameClock{
+			Black:         types.PlayerClock{RemainingMs: 60000},
+			White:         types.PlayerClock{RemainingMs: remaining.Milliseconds()},
+			TurnStartTime: now.Add(-10 * time.Second),
+		},
+		TakebackOffer: &types.TakebackOffer{
+			UserID:      "alice",
+			Color:       1,
+			MoveCount:   1,
+			RequestTime: now,
+			ExpireTime:  now.Add(takebackOfferTTL),
+		},
+		StartTime:  &now,
+		CreateTime: now,
+	}
+	for i, m := range []types.Move{{Row: 7, Col: 7}, {Row: 7, Col: 8}, {Row: 8, Col: 8}} {
+		m.Player = 1 + i%2
+		room.Board[m.Row][m.Col] = m.Player
+		room.MoveHistory = append(room.MoveHistory, m)
+	}
+	if err := s.CreateRoom(context.Background(), room); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func acceptTakebackRequest(t *testing.T) *types.GameRoom {
+	t.Helper()
+	accept := true
+	room, err := RespondTakeback(context.Background(), types.TakebackRespondRequest{UserID: "bob", RoomID: "r1", Accept: &accept})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return room
+}
+
+func TestAcceptTakebackChargesClock(t *testing.T) {
+	timedTakebackRoom(t, time.Minute)
+	room := acceptTakebackRequest(t)
+
+	if room.Status != "playing" || room.CurrentPlayer != 1 || len(room.MoveHistory) != 2 {
+		t.Fatalf("status %s, current player %d, %d moves; want the takeback applied", room.Status, room.CurrentPlayer, len(room.MoveHistory))
+	}
+	// 白方思考的 10 秒照常扣除，没有落子所以不加秒
+	if white := room.Clock.White.RemainingMs; white < 49000 || white > 50000 {
+		t.Errorf("white has %dms left, want about 50000", white)
+	}
+	if room.Clock.Black.RemainingMs != 60000 {
+		t.Errorf("black has %dms left, want 60000", room.Clock.Black.RemainingMs)
+	}
+	if time.Since(room.Clock.TurnStartTime) > time.Second {
+		t.Errorf("turn started at %v, want now", room.Clock.TurnStartTime)
+	}
+}
+
+func TestAcceptTakebackAfterTimeout(t *testing.T) {
+	timedTakebackRoom(t, 5*time.Second)
+	room := acceptTakebackRequest(t)
+
+	if room.Status != "finished" || room.Result == nil || room.Result.Reason != EndReasonTimeout || room.Result.WinnerColor != 1 {
+		t.Fatalf("status %s, result %+v; want white to lose on time", room.Status, room.Result)
+	}
+	if len(room.MoveHistory) != 3 {
+		t.Errorf("%d moves, want the takeback not applied", len(room.MoveHistory))
+	}
+	if room.Clock.White.RemainingMs != 0 {
+		t.Errorf("white has %dms left, want 0", room.Clock.White.RemainingMs)
+	}
+}
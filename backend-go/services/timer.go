@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// roomTimers 房间定时器（roomID -> 定时器）
+//
+// 人机对局轮到电脑时立即计算着法，对局中的房间等待当前一方超时，多局制比赛在局间等待下一局开始，
+// 同一房间同时只有一个定时器。
+// 定时器只是本实例上的提示，触发后会重新读取房间判断；多实例部署或重启后由 CheckRoomTimers 每分钟补偿一次，
+// 因此其他实例上的房间最多延迟约一分钟判定超时。
+var roomTimers = struct {
+	sync.Mutex
+	timers map[string]*time.Timer
+}{timers: make(map[string]*time.Timer)}
+
+// roomDeadline 返回房间下一次需要处理的时刻及处理函数，没有待处理事件时返回 nil
+func roomDeadline(room *types.GameRoom) (time.Time, func(ctx context.Context, roomID string) error) {
+	switch {
+	case isBotTurn(room):
+		return room.LastActionTime, PlayBotMove
+	case room.Status == "playing" && room.Clock != nil && room.TimeControl != nil:
+		return clockDeadline(room), CheckTimeout
+	case room.Status == "finished" && room.Match != nil && room.Match.NextGameTime != nil:
+		return *room.Match.NextGameTime, StartNextMatchGame
+	}
+	return time.Time{}, nil
+}
+
+// scheduleRoomTimer 按房间当前状态重新设置定时器，没有待处理事件时取消定时器
+func scheduleRoomTimer(room *types.GameRoom) {
+	roomTimers.Lock()
+	defer roomTimers.Unlock()
+
+	if timer, ok := roomTimers.timers[room.ID]; ok {
+		timer.Stop()
+		delete(roomTimers.timers, room.ID)
+	}
+
+	deadline, fire := roomDeadline(room)
+	if fire == nil {
+		return
+	}
+
+	roomID := room.ID
+	roomTimers.timers[roomID] = time.AfterFunc(time.Until(deadline), func() {
+		if err := fire(context.Background(), roomID); err != nil {
+			log.Printf("Error in timer of room %s: %v", roomID, err)
+		}
+	})
+}
+
+// cancelRoomTimer 取消房间的定时器
+func cancelRoomTimer(roomID string) {
+	roomTimers.Lock()
+	defer roomTimers.Unlock()
+
+	if timer, ok := roomTimers.timers[roomID]; ok {
+		timer.Stop()
+		delete(roomTimers.timers, roomID)
+	}
+}
+
+// CheckRoomTimers 检查所有有待处理事件的房间，处理已到期的事件并为其余房间补设定时器
+//
+// 需要列出所有对局中和已结束的房间，只应作为低频的补偿扫描调用。
+func CheckRoomTimers(ctx context.Context) error {
+	rooms, err := roomStore.ListRooms(ctx, []string{"playing", "finished"})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range rooms {
+		room := &rooms[i]
+		deadline, fire := roomDeadline(room)
+		if fire == nil {
+			continue
+		}
+		if now.Before(deadline) {
+			scheduleRoomTimer(room)
+			continue
+		}
+		if err := fire(ctx, room.ID); err != nil {
+			log.Printf("Error in timer of room %s: %v", room.ID, err)
+		}
+	}
+
+	return nil
+}
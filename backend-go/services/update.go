@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"gomoku-backend/store"
+	"gomoku-backend/types"
+)
+
+const (
+	// maxUpdateAttempts 并发冲突时最多尝试的次数
+	maxUpdateAttempts = 5
+	// retryBaseDelay 重试的基础等待时间，随尝试次数线性增长并附加随机抖动
+	retryBaseDelay = 20 * time.Millisecond
+)
+
+// retryOnConflict 执行一次完整的读-改-写操作，遇到 ETag 冲突时重新执行
+//
+// fn 每次都必须重新读取房间，且不能有除写库以外的副作用（推送消息等应在成功后进行）。
+func retryOnConflict(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !errors.Is(err, store.ErrConflict) {
+			return err
+		}
+		if attempt >= maxUpdateAttempts {
+			return ErrRoomConflict
+		}
+
+		delay := retryBaseDelay*time.Duration(attempt) + time.Duration(rand.Int63n(int64(retryBaseDelay)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// updateRoom 读取房间并调用 mutate 修改后写回，冲突时自动重试
+//
+// mutate 返回 false 表示无需写入，此时 changed 为 false。
+func updateRoom(ctx context.Context, roomID string, mutate func(room *types.GameRoom) (bool, error)) (room *types.GameRoom, changed bool, err error) {
+	err = retryOnConflict(ctx, func() error {
+		current, err := GetRoom(ctx, roomID)
+		if err != nil {
+			return err
+		}
+
+		changed, err = mutate(current)
+		if err != nil {
+			return err
+		}
+		if changed {
+			current.UpdateTime = time.Now()
+			if err := roomStore.ReplaceRoom(ctx, current); err != nil {
+				return err
+			}
+		}
+
+		room = current
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return room, changed, nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gomoku-backend/store"
+	"gomoku-backend/types"
+)
+
+// useMemoryStore 在测试期间使用内存房间存储
+func useMemoryStore(t *testing.T) *store.MemoryStore {
+	t.Helper()
+	s := store.NewMemoryStore()
+	previous := roomStore
+	SetRoomStore(s)
+	t.Cleanup(func() { roomStore = previous })
+	return s
+}
+
+// createTestRoom 在存储中创建等待中的房间
+func createTestRoom(t *testing.T, s *store.MemoryStore, id string) {
+	t.Helper()
+	room := &types.GameRoom{
+		ID:         id,
+		Players:    []types.Player{{UserID: "alice", Nickname: "alice", Color: 1}},
+		Status:     "waiting",
+		CreateTime: time.Now(),
+	}
+	if err := s.CreateRoom(context.Background(), room); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateRoomRetriesConflict(t *testing.T) {
+	ctx := context.Background()
+	s := useMemoryStore(t)
+	createTestRoom(t, s, "r1")
+
+	calls := 0
+	room, changed, err := updateRoom(ctx, "r1", func(room *types.GameRoom) (bool, error) {
+		calls++
+		if calls == 1 {
+			// 读取之后另一个请求先写入，本次写入的 ETag 已过期
+			other, err := s.GetRoom(ctx, "r1")
+			if err != nil {
+				return false, err
+			}
+			other.Spectators = append(other.Spectators, types.Spectator{UserID: "bob", Nickname: "bob"})
+			if err := s.ReplaceRoom(ctx, other); err != nil {
+				return false, err
+			}
+		}
+		room.TakebackLimit++
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || calls != 2 {
+		t.Fatalf("changed = %v after %d calls, want a retried write", changed, calls)
+	}
+
+	// 重试在对方写入的基础上修改，两次修改都保留
+	got, err := s.GetRoom(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TakebackLimit != 1 || len(got.Spectators) != 1 {
+		t.Errorf("room has takebackLimit %d and %d spectators, want 1 and 1", got.TakebackLimit, len(got.Spectators))
+	}
+	if room.ETag != got.ETag {
+		t.Errorf("returned ETag %s, stored %s", room.ETag, got.ETag)
+	}
+}
+
+func TestUpdateRoomGivesUp(t *testing.T) {
+	ctx := context.Background()
+	s := useMemoryStore(t)
+	createTestRoom(t, s, "r1")
+
+	calls := 0
+	_, _, err := updateRoom(ctx, "r1", func(room *types.GameRoom) (bool, error) {
+		calls++
+		// 每次读取之后都有其他请求先写入
+		other, err := s.GetRoom(ctx, "r1")
+		if err != nil {
+			return false, err
+		}
+		if err := s.ReplaceRoom(ctx, other); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if !errors.Is(err, ErrRoomConflict) {
+		t.Fatalf("err = %v, want ErrRoomConflict", err)
+	}
+	if calls != maxUpdateAttempts {
+		t.Errorf("tried %d times, want %d", calls, maxUpdateAttempts)
+	}
+}
+
+func TestUpdateRoomNotFound(t *testing.T) {
+	useMemoryStore(t)
+
+	_, _, err := updateRoom(context.Background(), "missing", func(room *types.GameRoom) (bool, error) {
+		t.Fatal("mutate called for a missing room")
+		return false, nil
+	})
+	if !errors.Is(err, store.ErrRoomNotFound) {
+		t.Errorf("err = %v, want ErrRoomNotFound", err)
+	}
+}
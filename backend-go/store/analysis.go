@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"gomoku-backend/config"
+	"gomoku-backend/types"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// ErrAnalysisNotFound 分析报告不存在
+var ErrAnalysisNotFound = errors.New("analysis not found")
+
+// AnalysisStore 赛后分析报告存储，以对局ID为键
+type AnalysisStore interface {
+	// GetAnalysis 根据对局ID获取分析报告
+	GetAnalysis(ctx context.Context, gameID string) (*types.GameAnalysis, error)
+	// SaveAnalysis 创建或覆盖分析报告
+	SaveAnalysis(ctx context.Context, analysis *types.GameAnalysis) error
+}
+
+// NewAnalysisStore 根据 STORAGE_DRIVER 环境变量创建分析报告存储，需在 New 之后调用
+//
+// Cosmos DB 下使用单独的容器（COSMOS_ANALYSIS_CONTAINER，默认 analyses）。
+func NewAnalysisStore(ctx context.Context) (AnalysisStore, error) {
+	driver := os.Getenv("STORAGE_DRIVER")
+	switch driver {
+	case "", "cosmos":
+		containerID := os.Getenv("COSMOS_ANALYSIS_CONTAINER")
+		if containerID == "" {
+			containerID = "analyses"
+		}
+		container, err := config.InitContainer(ctx, containerID)
+		if err != nil {
+			return nil, err
+		}
+		return NewCosmosAnalysisStore(container), nil
+	case "memory":
+		return NewMemoryAnalysisStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER: %s", driver)
+	}
+}
+
+// MemoryAnalysisStore 线程安全的内存分析报告存储
+type MemoryAnalysisStore struct {
+	mu       sync.RWMutex
+	analyses map[string][]byte
+}
+
+// NewMemoryAnalysisStore 创建内存分析报告存储
+func NewMemoryAnalysisStore() *MemoryAnalysisStore {
+	return &MemoryAnalysisStore{analyses: make(map[string][]byte)}
+}
+
+// GetAnalysis 获取分析报告
+func (s *MemoryAnalysisStore) GetAnalysis(ctx context.Context, gameID string) (*types.GameAnalysis, error) {
+	s.mu.RLock()
+	data, ok := s.analyses[gameID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrAnalysisNotFound
+	}
+
+	var analysis types.GameAnalysis
+	if err := json.Unmarshal(data, &analysis); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal analysis: %w", err)
+	}
+	return &analysis, nil
+}
+
+// SaveAnalysis 保存分析报告
+func (s *MemoryAnalysisStore) SaveAnalysis(ctx context.Context, analysis *types.GameAnalysis) error {
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.analyses[analysis.ID] = data
+	return nil
+}
+
+// CosmosAnalysisStore 基于 Cosmos DB 的分析报告存储（分区键: /id）
+type CosmosAnalysisStore struct {
+	container *azcosmos.ContainerClient
+}
+
+// NewCosmosAnalysisStore 创建 Cosmos DB 分析报告存储
+func NewCosmosAnalysisStore(container *azcosmos.ContainerClient) *CosmosAnalysisStore {
+	return &CosmosAnalysisStore{container: container}
+}
+
+// GetAnalysis 获取分析报告（点读）
+func (s *CosmosAnalysisStore) GetAnalysis(ctx context.Context, gameID string) (*types.GameAnalysis, error) {
+	resp, err := s.container.ReadItem(ctx, azcosmos.NewPartitionKeyString(gameID), gameID, nil)
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return nil, ErrAnalysisNotFound
+		}
+		return nil, fmt.Errorf("failed to read analysis: %w", err)
+	}
+
+	var analysis types.GameAnalysis
+	if err := json.Unmarshal(resp.Value, &analysis); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal analysis: %w", err)
+	}
+	return &analysis, nil
+}
+
+// SaveAnalysis 保存分析报告
+func (s *CosmosAnalysisStore) SaveAnalysis(ctx context.Context, analysis *types.GameAnalysis) error {
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis: %w", err)
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(analysis.ID)
+	if _, err := s.container.UpsertItem(ctx, partitionKey, data, nil); err != nil {
+		return fmt.Errorf("failed to save analysis: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"gomoku-backend/types"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// CosmosStore 基于 Cosmos DB 的房间存储（分区键: /id）
+type CosmosStore struct {
+	container *azcosmos.ContainerClient
+}
+
+// NewCosmosStore 创建 Cosmos DB 房间存储
+func NewCosmosStore(container *azcosmos.ContainerClient) *CosmosStore {
+	return &CosmosStore{container: container}
+}
+
+// cosmosDocument 带系统属性的房间文档
+type cosmosDocument struct {
+	types.GameRoom
+	ETag string `json:"_etag"`
+}
+
+// GetRoom 获取单个房间（点读）
+func (s *CosmosStore) GetRoom(ctx context.Context, roomID string) (*types.GameRoom, error) {
+	resp, err := s.container.ReadItem(ctx, azcosmos.NewPartitionKeyString(roomID), roomID, nil)
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return nil, ErrRoomNotFound
+		}
+		return nil, fmt.Errorf("failed to read room: %w", err)
+	}
+
+	var room types.GameRoom
+	if err := json.Unmarshal(resp.Value, &room); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal room: %w", err)
+	}
+	room.ETag = string(resp.ETag)
+	return &room, nil
+}
+
+// CreateRoom 创建房间
+func (s *CosmosStore) CreateRoom(ctx context.Context, room *types.GameRoom) error {
+	roomJSON, err := json.Marshal(room)
+	if err != nil {
+		return fmt.Errorf("failed to marshal room: %w", err)
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(room.ID)
+	resp, err := s.container.CreateItem(ctx, partitionKey, roomJSON, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create room: %w", err)
+	}
+	room.ETag = string(resp.ETag)
+	return nil
+}
+
+// ReplaceRoom 替换房间，状态变化也在同一次替换中完成
+func (s *CosmosStore) ReplaceRoom(ctx context.Context, room *types.GameRoom) error {
+	roomJSON, err := json.Marshal(room)
+	if err != nil {
+		return fmt.Errorf("failed to marshal room: %w", err)
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(room.ID)
+	resp, err := s.container.ReplaceItem(ctx, partitionKey, room.ID, roomJSON, ifMatch(room.ETag))
+	if err != nil {
+		return wrapWriteError("failed to update room", err)
+	}
+	room.ETag = string(resp.ETag)
+	return nil
+}
+
+// DeleteRoom 删除房间
+func (s *CosmosStore) DeleteRoom(ctx context.Context, room *types.GameRoom) error {
+	partitionKey := azcosmos.NewPartitionKeyString(room.ID)
+	if _, err := s.container.DeleteItem(ctx, partitionKey, room.ID, ifMatch(room.ETag)); err != nil {
+		return wrapWriteError("failed to delete room", err)
+	}
+	return nil
+}
+
+// ListRooms 获取房间列表
+func (s *CosmosStore) ListRooms(ctx context.Context, statuses []string) ([]types.GameRoom, error) {
+	// 跨分区查询由网关执行，不支持 ORDER BY，排序在本地完成
+	query := "SELECT * FROM c WHERE ARRAY_CONTAINS(@statuses, c.status)"
+	rooms, err := s.queryRooms(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@statuses", Value: statuses},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].CreateTime.After(rooms[j].CreateTime)
+	})
+	return rooms, nil
+}
+
+// FindRoomByUserID 根据用户ID查找房间
+func (s *CosmosStore) FindRoomByUserID(ctx context.Context, userID string) (*types.GameRoom, error) {
+	query := `SELECT * FROM c
+		WHERE EXISTS(SELECT VALUE p FROM p IN c.players WHERE p.userId = @userId)
+		OR EXISTS(SELECT VALUE s FROM s IN c.spectators WHERE s.userId = @userId)`
+
+	rooms, err := s.queryRooms(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@userId", Value: userID},
+	})
+	if err != nil || len(rooms) == 0 {
+		return nil, err
+	}
+	return &rooms[0], nil
+}
+
+// FindInactiveRooms 查找不活跃房间
+func (s *CosmosStore) FindInactiveRooms(ctx context.Context, before time.Time) ([]types.GameRoom, error) {
+	query := "SELECT * FROM c WHERE c.lastActionTime < @threshold"
+	return s.queryRooms(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@threshold", Value: before.Format(time.RFC3339)},
+	})
+}
+
+// queryRooms 执行跨分区查询，无法解析的文档记录日志后跳过
+func (s *CosmosStore) queryRooms(ctx context.Context, query string, params []azcosmos.QueryParameter) ([]types.GameRoom, error) {
+	var rooms []types.GameRoom
+
+	queryPager := s.container.NewQueryItemsPager(query, azcosmos.NewPartitionKey(), &azcosmos.QueryOptions{
+		QueryParameters: params,
+	})
+
+	for queryPager.More() {
+		response, err := queryPager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query rooms: %w", err)
+		}
+
+		for _, item := range response.Items {
+			var doc cosmosDocument
+			if err := json.Unmarshal(item, &doc); err != nil {
+				log.Printf("Failed to unmarshal room: %v", err)
+				continue
+			}
+			doc.GameRoom.ETag = doc.ETag
+			rooms = append(rooms, doc.GameRoom)
+		}
+	}
+
+	return rooms, nil
+}
+
+// ifMatch 构造带 ETag 前置条件的请求选项，etag 为空时不设前置条件
+func ifMatch(etag string) *azcosmos.ItemOptions {
+	if etag == "" {
+		return nil
+	}
+	e := azcore.ETag(etag)
+	return &azcosmos.ItemOptions{IfMatchEtag: &e}
+}
+
+// statusCode 提取 Cosmos DB 错误的 HTTP 状态码，非响应错误返回 0
+func statusCode(err error) int {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode
+	}
+	return 0
+}
+
+// wrapWriteError 将前置条件失败（412）以及文档已被删除（404）转换为 ErrConflict
+func wrapWriteError(msg string, err error) error {
+	switch statusCode(err) {
+	case http.StatusPreconditionFailed, http.StatusNotFound:
+		return fmt.Errorf("%s: %w", msg, ErrConflict)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
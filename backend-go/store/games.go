@@ -0,0 +1,231 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gomoku-backend/config"
+	"gomoku-backend/types"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// GameStore 对局记录存储，以对局ID为键，记录创建后不再修改
+type GameStore interface {
+	// GetGame 根据对局ID获取对局记录
+	GetGame(ctx context.Context, gameID string) (*types.GameRecord, error)
+	// CreateGame 保存对局记录，同一对局只保存一次
+	CreateGame(ctx context.Context, game *types.GameRecord) error
+	// ListUserGames 获取用户参与的对局，按结束时间倒序，跳过 offset 条后最多返回 limit 条，并返回总数
+	ListUserGames(ctx context.Context, userID string, offset, limit int) ([]types.GameRecord, int, error)
+}
+
+// NewGameStore 根据 STORAGE_DRIVER 环境变量创建对局记录存储，需在 New 之后调用
+//
+// Cosmos DB 下使用单独的容器（COSMOS_GAMES_CONTAINER，默认 games）。
+func NewGameStore(ctx context.Context) (GameStore, error) {
+	driver := os.Getenv("STORAGE_DRIVER")
+	switch driver {
+	case "", "cosmos":
+		containerID := os.Getenv("COSMOS_GAMES_CONTAINER")
+		if containerID == "" {
+			containerID = "games"
+		}
+		container, err := config.InitContainer(ctx, containerID)
+		if err != nil {
+			return nil, err
+		}
+		return NewCosmosGameStore(container), nil
+	case "memory":
+		return NewMemoryGameStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER: %s", driver)
+	}
+}
+
+// MemoryGameStore 线程安全的内存对局记录存储
+type MemoryGameStore struct {
+	mu    sync.RWMutex
+	games map[string]memoryGame
+}
+
+// memoryGame 以 JSON 保存对局记录，列表查询用到的字段单独保存
+type memoryGame struct {
+	data      []byte
+	playerIDs []string
+	endTime   time.Time
+}
+
+// NewMemoryGameStore 创建内存对局记录存储
+func NewMemoryGameStore() *MemoryGameStore {
+	return &MemoryGameStore{games: make(map[string]memoryGame)}
+}
+
+// GetGame 获取对局记录
+func (s *MemoryGameStore) GetGame(ctx context.Context, gameID string) (*types.GameRecord, error) {
+	s.mu.RLock()
+	game, ok := s.games[gameID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrGameNotFound
+	}
+	return decodeGame(game.data)
+}
+
+// CreateGame 保存对局记录
+func (s *MemoryGameStore) CreateGame(ctx context.Context, game *types.GameRecord) error {
+	data, err := json.Marshal(game)
+	if err != nil {
+		return fmt.Errorf("failed to marshal game: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.games[game.ID]; exists {
+		return fmt.Errorf("failed to create game: game %s already exists", game.ID)
+	}
+	s.games[game.ID] = memoryGame{
+		data:      data,
+		playerIDs: append([]string(nil), game.PlayerIDs...),
+		endTime:   game.EndTime,
+	}
+	return nil
+}
+
+// ListUserGames 获取用户参与的对局
+func (s *MemoryGameStore) ListUserGames(ctx context.Context, userID string, offset, limit int) ([]types.GameRecord, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []memoryGame
+	for _, game := range s.games {
+		for _, id := range game.playerIDs {
+			if id == userID {
+				matched = append(matched, game)
+				break
+			}
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].endTime.After(matched[j].endTime)
+	})
+
+	games := []types.GameRecord{}
+	for _, game := range page(matched, offset, limit) {
+		record, err := decodeGame(game.data)
+		if err != nil {
+			return nil, 0, err
+		}
+		games = append(games, *record)
+	}
+	return games, len(matched), nil
+}
+
+// CosmosGameStore 基于 Cosmos DB 的对局记录存储（分区键: /id）
+type CosmosGameStore struct {
+	container *azcosmos.ContainerClient
+}
+
+// NewCosmosGameStore 创建 Cosmos DB 对局记录存储
+func NewCosmosGameStore(container *azcosmos.ContainerClient) *CosmosGameStore {
+	return &CosmosGameStore{container: container}
+}
+
+// GetGame 获取对局记录（点读）
+func (s *CosmosGameStore) GetGame(ctx context.Context, gameID string) (*types.GameRecord, error) {
+	resp, err := s.container.ReadItem(ctx, azcosmos.NewPartitionKeyString(gameID), gameID, nil)
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return nil, ErrGameNotFound
+		}
+		return nil, fmt.Errorf("failed to read game: %w", err)
+	}
+	return decodeGame(resp.Value)
+}
+
+// CreateGame 保存对局记录，已存在时返回错误而不覆盖
+func (s *CosmosGameStore) CreateGame(ctx context.Context, game *types.GameRecord) error {
+	data, err := json.Marshal(game)
+	if err != nil {
+		return fmt.Errorf("failed to marshal game: %w", err)
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(game.ID)
+	if _, err := s.container.CreateItem(ctx, partitionKey, data, nil); err != nil {
+		return fmt.Errorf("failed to create game: %w", err)
+	}
+	return nil
+}
+
+// ListUserGames 获取用户参与的对局
+//
+// 跨分区查询由网关执行，不支持 ORDER BY 和 OFFSET，先只查询 ID 和结束时间在本地排序分页，再逐条点读。
+func (s *CosmosGameStore) ListUserGames(ctx context.Context, userID string, offset, limit int) ([]types.GameRecord, int, error) {
+	type gameKey struct {
+		ID      string    `json:"id"`
+		EndTime time.Time `json:"endTime"`
+	}
+
+	query := "SELECT c.id, c.endTime FROM c WHERE ARRAY_CONTAINS(c.playerIds, @userId)"
+	queryPager := s.container.NewQueryItemsPager(query, azcosmos.NewPartitionKey(), &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{{Name: "@userId", Value: userID}},
+	})
+
+	var keys []gameKey
+	for queryPager.More() {
+		response, err := queryPager.NextPage(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to query games: %w", err)
+		}
+		for _, item := range response.Items {
+			var key gameKey
+			if err := json.Unmarshal(item, &key); err != nil {
+				log.Printf("Failed to unmarshal game: %v", err)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].EndTime.After(keys[j].EndTime)
+	})
+
+	games := []types.GameRecord{}
+	for _, key := range page(keys, offset, limit) {
+		game, err := s.GetGame(ctx, key.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		games = append(games, *game)
+	}
+	return games, len(keys), nil
+}
+
+// decodeGame 解析对局记录
+func decodeGame(data []byte) (*types.GameRecord, error) {
+	var game types.GameRecord
+	if err := json.Unmarshal(data, &game); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal game: %w", err)
+	}
+	return &game, nil
+}
+
+// page 返回跳过 offset 个元素后的最多 limit 个元素
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
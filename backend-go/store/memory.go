@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// MemoryStore 线程安全的内存房间存储，用于本地开发和单元测试
+type MemoryStore struct {
+	mu      sync.RWMutex
+	rooms   map[string]memoryDocument
+	version uint64
+}
+
+// memoryDocument 以 JSON 保存房间，保证读写双方互不共享切片
+type memoryDocument struct {
+	data []byte
+	etag string
+}
+
+// NewMemoryStore 创建内存房间存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{rooms: make(map[string]memoryDocument)}
+}
+
+// GetRoom 获取单个房间
+func (s *MemoryStore) GetRoom(ctx context.Context, roomID string) (*types.GameRoom, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.rooms[roomID]
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	return doc.decode()
+}
+
+// CreateRoom 创建房间
+func (s *MemoryStore) CreateRoom(ctx context.Context, room *types.GameRoom) error {
+	data, err := json.Marshal(room)
+	if err != nil {
+		return fmt.Errorf("failed to marshal room: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.rooms[room.ID]; exists {
+		return fmt.Errorf("failed to create room: room %s already exists", room.ID)
+	}
+	s.put(room, data)
+	return nil
+}
+
+// ReplaceRoom 替换房间
+func (s *MemoryStore) ReplaceRoom(ctx context.Context, room *types.GameRoom) error {
+	data, err := json.Marshal(room)
+	if err != nil {
+		return fmt.Errorf("failed to marshal room: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkETag(room); err != nil {
+		return fmt.Errorf("failed to update room: %w", err)
+	}
+	s.put(room, data)
+	return nil
+}
+
+// DeleteRoom 删除房间
+func (s *MemoryStore) DeleteRoom(ctx context.Context, room *types.GameRoom) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkETag(room); err != nil {
+		return fmt.Errorf("failed to delete room: %w", err)
+	}
+	delete(s.rooms, room.ID)
+	return nil
+}
+
+// ListRooms 获取房间列表
+func (s *MemoryStore) ListRooms(ctx context.Context, statuses []string) ([]types.GameRoom, error) {
+	wanted := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		wanted[status] = true
+	}
+
+	rooms, err := s.filter(func(room *types.GameRoom) bool {
+		return wanted[room.Status]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].CreateTime.After(rooms[j].CreateTime)
+	})
+	return rooms, nil
+}
+
+// FindRoomByUserID 根据用户ID查找房间
+func (s *MemoryStore) FindRoomByUserID(ctx context.Context, userID string) (*types.GameRoom, error) {
+	rooms, err := s.filter(func(room *types.GameRoom) bool {
+		for _, p := range room.Players {
+			if p.UserID == userID {
+				return true
+			}
+		}
+		for _, sp := range room.Spectators {
+			if sp.UserID == userID {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil || len(rooms) == 0 {
+		return nil, err
+	}
+	return &rooms[0], nil
+}
+
+// FindInactiveRooms 查找不活跃房间
+func (s *MemoryStore) FindInactiveRooms(ctx context.Context, before time.Time) ([]types.GameRoom, error) {
+	return s.filter(func(room *types.GameRoom) bool {
+		return room.LastActionTime.Before(before)
+	})
+}
+
+// filter 返回满足条件的房间副本
+func (s *MemoryStore) filter(match func(room *types.GameRoom) bool) ([]types.GameRoom, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rooms []types.GameRoom
+	for _, doc := range s.rooms {
+		room, err := doc.decode()
+		if err != nil {
+			return nil, err
+		}
+		if match(room) {
+			rooms = append(rooms, *room)
+		}
+	}
+	return rooms, nil
+}
+
+// checkETag 校验房间 ETag 与存储中的文档一致，调用方需持有写锁
+func (s *MemoryStore) checkETag(room *types.GameRoom) error {
+	doc, exists := s.rooms[room.ID]
+	if !exists || (room.ETag != "" && room.ETag != doc.etag) {
+		return ErrConflict
+	}
+	return nil
+}
+
+// put 保存文档并生成新的 ETag，调用方需持有写锁
+func (s *MemoryStore) put(room *types.GameRoom, data []byte) {
+	s.version++
+	room.ETag = fmt.Sprintf("\"%d\"", s.version)
+	s.rooms[room.ID] = memoryDocument{data: data, etag: room.ETag}
+}
+
+// decode 反序列化房间文档
+func (d memoryDocument) decode() (*types.GameRoom, error) {
+	var room types.GameRoom
+	if err := json.Unmarshal(d.data, &room); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal room: %w", err)
+	}
+	room.ETag = d.etag
+	return &room, nil
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gomoku-backend/types"
+)
+
+// newRoom 创建只有一名玩家的等待中房间
+func newRoom(id, userID string, lastAction time.Time) *types.GameRoom {
+	return &types.GameRoom{
+		ID:             id,
+		Players:        []types.Player{{UserID: userID, Nickname: userID, Color: 1}},
+		Status:         "waiting",
+		CreateTime:     lastAction,
+		LastActionTime: lastAction,
+	}
+}
+
+func TestMemoryStoreReplace(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	room := newRoom("r1", "alice", time.Now())
+	if err := s.CreateRoom(ctx, room); err != nil {
+		t.Fatal(err)
+	}
+	if room.ETag == "" {
+		t.Fatal("CreateRoom did not set the ETag")
+	}
+	if err := s.CreateRoom(ctx, newRoom("r1", "bob", time.Now())); err == nil {
+		t.Error("CreateRoom with an existing ID succeeded")
+	}
+
+	first, err := s.GetRoom(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.GetRoom(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ETag != room.ETag || first.Players[0].UserID != "alice" {
+		t.Fatalf("GetRoom = %+v, want the created room", first)
+	}
+
+	// 读到的房间互不共享切片
+	first.Players[0].Nickname = "changed"
+	if second.Players[0].Nickname != "alice" {
+		t.Error("rooms read from the store share players")
+	}
+
+	first.Status = "playing"
+	if err := s.ReplaceRoom(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if first.ETag == second.ETag {
+		t.Error("ReplaceRoom did not change the ETag")
+	}
+
+	// 第二份副本的 ETag 已过期
+	second.Status = "finished"
+	if err := s.ReplaceRoom(ctx, second); !errors.Is(err, ErrConflict) {
+		t.Errorf("ReplaceRoom with a stale ETag: err = %v, want ErrConflict", err)
+	}
+	if err := s.DeleteRoom(ctx, second); !errors.Is(err, ErrConflict) {
+		t.Errorf("DeleteRoom with a stale ETag: err = %v, want ErrConflict", err)
+	}
+
+	got, err := s.GetRoom(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "playing" || got.ETag != first.ETag {
+		t.Errorf("room = %s %s, want the first write %s %s", got.Status, got.ETag, "playing", first.ETag)
+	}
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	room := newRoom("r1", "alice", time.Now())
+	if err := s.CreateRoom(ctx, room); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteRoom(ctx, room); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetRoom(ctx, "r1"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("GetRoom after delete: err = %v, want ErrRoomNotFound", err)
+	}
+
+	// 已删除的房间不能再写入或删除
+	if err := s.ReplaceRoom(ctx, room); !errors.Is(err, ErrConflict) {
+		t.Errorf("ReplaceRoom after delete: err = %v, want ErrConflict", err)
+	}
+	if err := s.DeleteRoom(ctx, room); !errors.Is(err, ErrConflict) {
+		t.Errorf("DeleteRoom after delete: err = %v, want ErrConflict", err)
+	}
+}
+
+func TestMemoryStoreFindRoomByUserID(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	room := newRoom("r1", "alice", time.Now())
+	room.Spectators = []types.Spectator{{UserID: "carol", Nickname: "carol"}}
+	if err := s.CreateRoom(ctx, room); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateRoom(ctx, newRoom("r2", "bob", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		userID string
+		want   string // 房间ID，为空表示未找到
+	}{
+		{userID: "alice", want: "r1"},
+		{userID: "carol", want: "r1"},
+		{userID: "bob", want: "r2"},
+		{userID: "dave"},
+	}
+	for _, tt := range tests {
+		got, err := s.FindRoomByUserID(ctx, tt.userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case tt.want == "" && got != nil:
+			t.Errorf("%s: found room %s, want none", tt.userID, got.ID)
+		case tt.want != "" && (got == nil || got.ID != tt.want):
+			t.Errorf("%s: room = %v, want %s", tt.userID, got, tt.want)
+		case got != nil && got.ETag == "":
+			t.Errorf("%s: room has no ETag", tt.userID)
+		}
+	}
+}
+
+func TestMemoryStoreFindInactiveRooms(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	now := time.Now()
+	for id, lastAction := range map[string]time.Time{
+		"idle":   now.Add(-time.Hour),
+		"recent": now.Add(-time.Minute),
+	} {
+		if err := s.CreateRoom(ctx, newRoom(id, id, lastAction)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rooms, err := s.FindInactiveRooms(ctx, now.Add(-10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms) != 1 || rooms[0].ID != "idle" {
+		t.Fatalf("inactive rooms = %v, want only idle", rooms)
+	}
+
+	// 返回的房间带有 ETag，可以直接删除
+	if err := s.DeleteRoom(ctx, &rooms[0]); err != nil {
+		t.Errorf("DeleteRoom of an inactive room: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gomoku-backend/config"
+	"gomoku-backend/types"
+)
+
+var (
+	// ErrRoomNotFound 房间不存在
+	ErrRoomNotFound = errors.New("room not found")
+	// ErrGameNotFound 对局不存在
+	ErrGameNotFound = errors.New("game not found")
+	// ErrConflict 房间已被其他请求修改（ETag 不匹配）
+	ErrConflict = errors.New("room was modified concurrently")
+)
+
+// RoomStore 房间存储接口
+//
+// 读取到的房间携带 ETag，ReplaceRoom/DeleteRoom 以其作为前置条件，
+// 文档已被修改时返回 ErrConflict；写入成功后房间的 ETag 会被更新。
+type RoomStore interface {
+	// GetRoom 根据房间ID获取房间
+	GetRoom(ctx context.Context, roomID string) (*types.GameRoom, error)
+	// CreateRoom 创建房间文档
+	CreateRoom(ctx context.Context, room *types.GameRoom) error
+	// ReplaceRoom 替换房间文档
+	ReplaceRoom(ctx context.Context, room *types.GameRoom) error
+	// DeleteRoom 删除房间文档
+	DeleteRoom(ctx context.Context, room *types.GameRoom) error
+	// ListRooms 获取指定状态的房间列表，按创建时间倒序
+	ListRooms(ctx context.Context, statuses []string) ([]types.GameRoom, error)
+	// FindRoomByUserID 查找用户（玩家或旁观者）所在的房间，未找到时返回 nil
+	FindRoomByUserID(ctx context.Context, userID string) (*types.GameRoom, error)
+	// FindInactiveRooms 查找最后活动时间早于 before 的房间
+	FindInactiveRooms(ctx context.Context, before time.Time) ([]types.GameRoom, error)
+}
+
+// New 根据 STORAGE_DRIVER 环境变量创建房间存储（cosmos 或 memory，默认 cosmos）
+func New(ctx context.Context) (RoomStore, error) {
+	driver := os.Getenv("STORAGE_DRIVER")
+	switch driver {
+	case "", "cosmos":
+		if err := config.InitDatabase(ctx); err != nil {
+			return nil, err
+		}
+		return NewCosmosStore(config.GetContainer()), nil
+	case "memory":
+		log.Println("Using in-memory room store, data will be lost on restart")
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER: %s", driver)
+	}
+}
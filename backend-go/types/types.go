@@ -8,6 +8,9 @@ type Player struct {
 	Nickname string `json:"nickname"`
 	Color    int    `json:"color"` // 1: 黑子, 2: 白子
 	IsReady  bool   `json:"isReady"`
+
+	TakebacksUsed int  `json:"takebacksUsed,omitempty"` // 本局已悔棋次数
+	IsBot         bool `json:"isBot,omitempty"`         // 服务器控制的电脑玩家
 }
 
 // Spectator 旁观者信息
@@ -19,9 +22,16 @@ type Spectator struct {
 
 // Move 下棋记录
 type Move struct {
-	Row    int `json:"row"`
-	Col    int `json:"col"`
-	Player int `json:"player"`
+	Row    int        `json:"row"`
+	Col    int        `json:"col"`
+	Player int        `json:"player"`
+	Time   *time.Time `json:"time,omitempty"` // 落子时间（服务器时间），导入的记录没有
+}
+
+// Point 棋盘坐标
+type Point struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
 }
 
 // Creator 创建者信息
@@ -30,27 +40,321 @@ type Creator struct {
 	Nickname string `json:"nickname"`
 }
 
+// TimeControl 时间控制
+type TimeControl struct {
+	Mode             string `json:"mode"`                       // absolute（包干）, fischer（每步加秒）, byoyomi（日式读秒）
+	InitialSeconds   int    `json:"initialSeconds"`             // 基本用时（秒）
+	IncrementSeconds int    `json:"incrementSeconds,omitempty"` // fischer: 每步加秒
+	ByoyomiSeconds   int    `json:"byoyomiSeconds,omitempty"`   // byoyomi: 每次读秒的时长
+	ByoyomiPeriods   int    `json:"byoyomiPeriods,omitempty"`   // byoyomi: 读秒次数
+}
+
+// PlayerClock 一方的剩余时间
+type PlayerClock struct {
+	RemainingMs int64 `json:"remainingMs"`       // 剩余基本用时（毫秒），byoyomi 下为 0 表示已进入读秒
+	Periods     int   `json:"periods,omitempty"` // byoyomi: 剩余读秒次数
+}
+
+// GameClock 对局时钟，剩余时间截至 TurnStartTime
+type GameClock struct {
+	Black         PlayerClock `json:"black"`
+	White         PlayerClock `json:"white"`
+	TurnStartTime time.Time   `json:"turnStartTime"` // 当前一方开始计时的服务器时间
+}
+
+// DrawOffer 待回应的和棋提议
+type DrawOffer struct {
+	UserID     string    `json:"userId"` // 提议方
+	Color      int       `json:"color"`
+	OfferTime  time.Time `json:"offerTime"`
+	ExpireTime time.Time `json:"expireTime"` // 超过此时间未回应则提议失效
+}
+
+// TakebackOffer 待回应的悔棋请求
+type TakebackOffer struct {
+	UserID      string    `json:"userId"` // 请求方
+	Color       int       `json:"color"`
+	MoveCount   int       `json:"moveCount"` // 同意后撤回的步数（1 或 2）
+	RequestTime time.Time `json:"requestTime"`
+	ExpireTime  time.Time `json:"expireTime"`
+}
+
+// RematchOffer 待回应的再来一局提议
+type RematchOffer struct {
+	UserID     string    `json:"userId"` // 提议方
+	OfferTime  time.Time `json:"offerTime"`
+	ExpireTime time.Time `json:"expireTime"`
+}
+
+// GameSummary 房间内已结束的一局，不含着法，完整记录见归档的 GameRecord
+type GameSummary struct {
+	ID           string     `json:"id"`         // 对局ID，用于获取赛后分析等
+	GameNumber   int        `json:"gameNumber"` // 从 1 开始
+	Players      []Player   `json:"players"`    // 该局双方及执子颜色
+	WinnerID     string     `json:"winnerId,omitempty"`
+	WinnerColor  int        `json:"winnerColor"` // 0 表示平局
+	EndReason    string     `json:"endReason"`
+	WinLine      []Point    `json:"winLine,omitempty"`      // 五连获胜时获胜的连子
+	Moves        int        `json:"moves"`                  // 着法数
+	OpeningMoves int        `json:"openingMoves,omitempty"` // 开局阶段摆放的棋子数
+	StartTime    *time.Time `json:"startTime,omitempty"`
+	EndTime      time.Time  `json:"endTime"`
+}
+
+// MatchConfig 多局制比赛设置
+type MatchConfig struct {
+	BestOf       int `json:"bestOf"`       // 3, 5, 7
+	PauseSeconds int `json:"pauseSeconds"` // 每局结束后自动开始下一局前的间隔（秒）
+}
+
+// Match 多局制比赛状态，双方每局交换颜色
+type Match struct {
+	BestOf       int            `json:"bestOf"`
+	PauseSeconds int            `json:"pauseSeconds"`
+	Status       string         `json:"status"` // playing, finished
+	Wins         map[string]int `json:"wins"`   // userId -> 胜局数
+	Draws        int            `json:"draws"`
+	GamesPlayed  int            `json:"gamesPlayed"`
+	WinnerID     string         `json:"winnerId,omitempty"`     // 比赛结束且分出胜负时
+	NextGameTime *time.Time     `json:"nextGameTime,omitempty"` // 下一局自动开始的时间
+}
+
+// BotConfig 人机对局设置
+type BotConfig struct {
+	Level  string `json:"level,omitempty"`  // easy, medium, hard，使用外部引擎时忽略
+	Engine string `json:"engine,omitempty"` // 外部引擎名称（Piskvork 协议），为空时使用内置引擎
+	Color  int    `json:"color"`            // 电脑执子颜色，默认 2（白）
+	Seed   int64  `json:"seed,omitempty"`   // 非 0 时电脑只按节点数限制搜索，相同种子和局面下着法可复现
+}
+
+// OpeningState 开局阶段（swap/swap2）的进度
+type OpeningState struct {
+	Phase  string `json:"phase"`  // place3（摆前三子）, choose（选择颜色）, place2（swap2: 再摆两子）, choose2（swap2: 选择颜色）
+	UserID string `json:"userId"` // 当前需要摆子或选择的玩家
+}
+
+// ReviewState 复盘房间展示的对局信息，棋盘和着法在房间的 Board、MoveHistory 中
+type ReviewState struct {
+	GameID      string `json:"gameId,omitempty"` // 回放的归档对局，导入的记录为空
+	Event       string `json:"event,omitempty"`
+	Black       string `json:"black,omitempty"` // 黑方名称
+	White       string `json:"white,omitempty"` // 白方名称
+	WinnerColor int    `json:"winnerColor"`     // 0 表示平局或未分胜负
+	EndReason   string `json:"endReason,omitempty"`
+	Moves       []Move `json:"moves"`  // 完整着法，房间的棋盘和 moveHistory 为其中前 cursor 步
+	Cursor      int    `json:"cursor"` // 当前显示到第几步，0 为空棋盘
+}
+
+// GameResult 一局棋的结果
+type GameResult struct {
+	WinnerID    string  `json:"winnerId,omitempty"` // 平局时为空
+	WinnerColor int     `json:"winnerColor"`        // 0 表示平局
+	Reason      string  `json:"reason"`             // five, resign, timeout, draw, abandon, forbidden-move
+	WinLine     []Point `json:"winLine,omitempty"`  // 五连获胜时获胜的连子，从一端到另一端
+}
+
 // GameRoom 游戏房间
 type GameRoom struct {
-	ID             string      `json:"id"`
-	RoomNumber     int         `json:"roomNumber"`
-	Creator        Creator     `json:"creator"`
-	Players        []Player    `json:"players"`
-	Spectators     []Spectator `json:"spectators"`
-	Board          [][]int     `json:"board"`
-	CurrentPlayer  int         `json:"currentPlayer"`
-	Status         string      `json:"status"` // waiting, playing, finished
-	MoveHistory    []Move      `json:"moveHistory"`
-	Winner         *string     `json:"winner"`
-	CreateTime     time.Time   `json:"createTime"`
-	UpdateTime     time.Time   `json:"updateTime"`
-	LastActionTime time.Time   `json:"lastActionTime"`
+	ID              string         `json:"id"`
+	RoomNumber      int            `json:"roomNumber"`
+	Creator         Creator        `json:"creator"`
+	Players         []Player       `json:"players"`
+	Spectators      []Spectator    `json:"spectators"`
+	Board           [][]int        `json:"board"`
+	BoardSize       int            `json:"boardSize"` // 棋盘边长（9-19）
+	CurrentPlayer   int            `json:"currentPlayer"`
+	Status          string         `json:"status"`                    // waiting, opening, playing, finished, review
+	Rules           string         `json:"rules"`                     // freestyle, standard, caro, renju
+	ForbiddenPolicy string         `json:"forbiddenPolicy,omitempty"` // 连珠黑方禁手: reject（拒绝落子）, lose（判负）
+	TimeControl     *TimeControl   `json:"timeControl,omitempty"`
+	Clock           *GameClock     `json:"clock,omitempty"`
+	DrawOffer       *DrawOffer     `json:"drawOffer,omitempty"`
+	Rated           bool           `json:"rated"`         // 排位对局不允许悔棋
+	TakebackLimit   int            `json:"takebackLimit"` // 每位玩家每局可悔棋次数
+	TakebackOffer   *TakebackOffer `json:"takebackOffer,omitempty"`
+	RematchOffer    *RematchOffer  `json:"rematchOffer,omitempty"`
+	Series          []GameSummary  `json:"series,omitempty"` // 本房间最近结束的对局，最多保留 20 局
+	Match           *Match         `json:"match,omitempty"`
+	Opening         string         `json:"opening,omitempty"` // 开局规则: swap, swap2
+	OpeningState    *OpeningState  `json:"openingState,omitempty"`
+	OpeningMoves    int            `json:"openingMoves,omitempty"` // 开局阶段摆放的棋子数，这些棋子不能悔棋
+	Bot             *BotConfig     `json:"bot,omitempty"`          // 人机对局
+	Review          *ReviewState   `json:"review,omitempty"`       // 复盘房间展示的对局
+	MoveHistory     []Move         `json:"moveHistory"`
+	Result          *GameResult    `json:"result,omitempty"`    // 当前一局的结果，对局结束后才有
+	Winner          *string        `json:"winner"`              // 已废弃，获胜方昵称或"平局"，过渡期内保留，请使用 result
+	EndReason       string         `json:"endReason,omitempty"` // 已废弃，同 result.reason，过渡期内保留，请使用 result
+	StartTime       *time.Time     `json:"startTime,omitempty"` // 当前一局开始时间
+	CreateTime      time.Time      `json:"createTime"`
+	UpdateTime      time.Time      `json:"updateTime"`
+	LastActionTime  time.Time      `json:"lastActionTime"`
+	ETag            string         `json:"-"` // 文档版本，用于乐观并发控制
 }
 
-// CreateRoomRequest 创建房间请求
-type CreateRoomRequest struct {
+// CandidateMove 候选落子及评分（行棋方角度，越大越好）
+type CandidateMove struct {
+	Row   int `json:"row"`
+	Col   int `json:"col"`
+	Score int `json:"score"`
+}
+
+// PositionAnalysis 局面分析结果
+type PositionAnalysis struct {
+	Color      int             `json:"color"`               // 行棋方
+	Verdict    string          `json:"verdict"`             // 行棋方角度: win（必胜）, loss（必败）, unclear
+	Best       CandidateMove   `json:"best"`                // 推荐落子
+	Candidates []CandidateMove `json:"candidates"`          // 评分最高的若干候选点，按评分降序
+	PV         []Move          `json:"pv"`                  // 主要变化，从推荐落子开始双方交替
+	ForcedWin  []Move          `json:"forcedWin,omitempty"` // verdict 不是 unclear 时为获胜一方的必胜序列（含双方着法）
+	Depth      int             `json:"depth"`               // 完成的搜索深度
+	Nodes      int             `json:"nodes"`
+}
+
+// SolveResult 威胁空间搜索结果
+type SolveResult struct {
+	Color   int    `json:"color"` // 进攻方
+	Mode    string `json:"mode"`  // vcf 或 vct
+	Found   bool   `json:"found"`
+	Line    []Move `json:"line"`              // 必胜序列，进攻方先行、双方交替，以进攻方成五结束
+	Nodes   int    `json:"nodes"`             // 搜索的节点数
+	Aborted bool   `json:"aborted,omitempty"` // 达到节点数或时间上限而停止，此时未找到不代表不存在
+	// DefenderForbidden 最后一手冲四的成五点是防守方的禁手，line 的最后两手都是进攻方的
+	DefenderForbidden bool `json:"defenderForbidden,omitempty"`
+}
+
+// MoveAnalysis 赛后分析中的一步棋，评分均为落子方角度
+type MoveAnalysis struct {
+	MoveNumber  int           `json:"moveNumber"`      // 从 1 开始
+	Move        Move          `json:"move"`            // 实际落子
+	Best        CandidateMove `json:"best"`            // 该局面的最佳落子及评分
+	PlayedScore int           `json:"playedScore"`     // 实际落子后的评分
+	Loss        int           `json:"loss"`            // 相对最佳落子损失的评分
+	Label       string        `json:"label,omitempty"` // blunder（败着）, mistake（失误）, missed-win（错过必胜）
+}
+
+// AnalysisStats 一方的失误统计
+type AnalysisStats struct {
+	Blunders   int `json:"blunders"`
+	Mistakes   int `json:"mistakes"`
+	MissedWins int `json:"missedWins"`
+}
+
+// GameRecord 归档的一局棋，对局结束时保存，之后不再修改，不随房间删除
+type GameRecord struct {
+	ID              string       `json:"id"` // 与 GameSummary.ID 相同
+	RoomID          string       `json:"roomId"`
+	RoomNumber      int          `json:"roomNumber"`
+	GameNumber      int          `json:"gameNumber"`
+	Players         []Player     `json:"players"`   // 双方及执子颜色
+	PlayerIDs       []string     `json:"playerIds"` // 用于按用户查询
+	BoardSize       int          `json:"boardSize"`
+	Rules           string       `json:"rules"`
+	ForbiddenPolicy string       `json:"forbiddenPolicy,omitempty"`
+	Opening         string       `json:"opening,omitempty"`
+	OpeningMoves    int          `json:"openingMoves,omitempty"`
+	TimeControl     *TimeControl `json:"timeControl,omitempty"`
+	Rated           bool         `json:"rated"`
+	Bot             *BotConfig   `json:"bot,omitempty"`
+	MoveHistory     []Move       `json:"moveHistory"`
+	WinnerID        string       `json:"winnerId,omitempty"`
+	WinnerColor     int          `json:"winnerColor"` // 0 表示平局
+	EndReason       string       `json:"endReason"`
+	WinLine         []Point      `json:"winLine,omitempty"` // 五连获胜时获胜的连子
+	StartTime       *time.Time   `json:"startTime,omitempty"`
+	EndTime         time.Time    `json:"endTime"`
+}
+
+// GameList 分页的对局记录列表，按结束时间倒序
+type GameList struct {
+	Games  []GameRecord `json:"games"`
+	Total  int          `json:"total"`
+	Offset int          `json:"offset"`
+	Limit  int          `json:"limit"`
+}
+
+// GameAnalysis 赛后分析报告
+type GameAnalysis struct {
+	ID         string         `json:"id"` // 对局ID
+	RoomID     string         `json:"roomId"`
+	Status     string         `json:"status"` // pending, running, done, failed
+	Rules      string         `json:"rules"`
+	BoardSize  int            `json:"boardSize"`
+	Moves      []MoveAnalysis `json:"moves"` // 开局阶段的摆子不分析
+	Black      AnalysisStats  `json:"black"`
+	White      AnalysisStats  `json:"white"`
+	Error      string         `json:"error,omitempty"` // failed 时的原因
+	CreateTime time.Time      `json:"createTime"`
+	UpdateTime time.Time      `json:"updateTime"`
+}
+
+// AnalyzeRequest 局面分析请求，board 和 moves 二选一
+type AnalyzeRequest struct {
+	Board     [][]int `json:"board"`     // 当前棋盘，0 空 1 黑 2 白
+	Moves     []Move  `json:"moves"`     // 从空棋盘开始的着法，player 为 0 时按黑白交替
+	BoardSize int     `json:"boardSize"` // 使用 moves 时的棋盘边长，默认 15
+	Rules     string  `json:"rules"`     // freestyle（默认）, standard, caro, renju
+	Color     int     `json:"color"`     // 行棋方，不传时根据双方子数推断
+	TopN      int     `json:"topN"`      // 返回的候选点数，默认 3，最多 10
+}
+
+// ImportGameRequest 导入对局记录创建复盘房间请求
+type ImportGameRequest struct {
+	UserID    string `json:"userId" binding:"required"`
+	Nickname  string `json:"nickname" binding:"required"`
+	Content   string `json:"content" binding:"required"` // 对局文件内容
+	Format    string `json:"format"`                     // psq, sgf, renju，不传时根据内容判断
+	Rules     string `json:"rules"`                      // 校验着法使用的规则，不传时使用文件中的规则，默认 freestyle
+	BoardSize int    `json:"boardSize"`                  // 文件没有给出棋盘大小时使用，默认 15
+}
+
+// ReplayGameRequest 回放归档对局创建复盘房间请求
+type ReplayGameRequest struct {
 	UserID   string `json:"userId" binding:"required"`
 	Nickname string `json:"nickname" binding:"required"`
+	GameID   string `json:"gameId" binding:"required"`
+}
+
+// ReviewStepRequest 复盘房间跳转到指定步数请求，由房主操作
+type ReviewStepRequest struct {
+	RoomID string `json:"roomId" binding:"required"`
+	UserID string `json:"userId" binding:"required"`
+	Move   *int   `json:"move" binding:"required"` // 显示前几步，0 为空棋盘
+}
+
+// GamePosition 归档对局第 move 步之后的局面
+type GamePosition struct {
+	GameID        string  `json:"gameId"`
+	Move          int     `json:"move"`       // 已下的步数
+	TotalMoves    int     `json:"totalMoves"` // 整局的步数
+	Board         [][]int `json:"board"`
+	CurrentPlayer int     `json:"currentPlayer"`      // 下一步的行棋方
+	LastMove      *Move   `json:"lastMove,omitempty"` // 第 move 步，move 为 0 时为空
+}
+
+// SolveRequest 威胁空间搜索请求，board 和 moves 二选一
+type SolveRequest struct {
+	Board     [][]int `json:"board"`     // 当前棋盘，0 空 1 黑 2 白
+	Moves     []Move  `json:"moves"`     // 从空棋盘开始的着法，player 为 0 时按黑白交替
+	BoardSize int     `json:"boardSize"` // 使用 moves 时的棋盘边长，默认 15
+	Rules     string  `json:"rules"`     // freestyle（默认）, standard, caro, renju
+	Color     int     `json:"color"`     // 进攻方，不传时根据双方子数推断
+	Mode      string  `json:"mode"`      // vcf（默认）或 vct
+	MaxNodes  int     `json:"maxNodes"`  // 搜索节点数上限，不传时使用默认值，不能超过默认值
+}
+
+// CreateRoomRequest 创建房间请求
+type CreateRoomRequest struct {
+	UserID          string       `json:"userId" binding:"required"`
+	Nickname        string       `json:"nickname" binding:"required"`
+	Rules           string       `json:"rules"`           // freestyle（默认）, standard, caro, renju
+	ForbiddenPolicy string       `json:"forbiddenPolicy"` // reject（默认）, lose
+	BoardSize       int          `json:"boardSize"`       // 9-19，默认 15
+	TimeControl     *TimeControl `json:"timeControl"`     // 不传表示不计时
+	Rated           bool         `json:"rated"`           // 排位对局
+	TakebackLimit   *int         `json:"takebackLimit"`   // 每位玩家每局可悔棋次数，默认 3，排位对局固定为 0
+	Match           *MatchConfig `json:"match"`           // 多局制比赛，不传表示单局
+	Opening         string       `json:"opening"`         // 开局规则: swap, swap2，不传表示黑方直接开局
+	Bot             *BotConfig   `json:"bot"`             // 与电脑对局，不传表示等待其他玩家加入
 }
 
 // JoinRoomRequest 加入房间请求
@@ -64,8 +368,8 @@ type JoinRoomRequest struct {
 type MakeMoveRequest struct {
 	UserID string `json:"userId" binding:"required"`
 	RoomID string `json:"roomId" binding:"required"`
-	Row    int    `json:"row" binding:"required"`
-	Col    int    `json:"col" binding:"required"`
+	Row    int    `json:"row"` // 0 是合法坐标，不能使用 required，范围在 MakeMove 中校验
+	Col    int    `json:"col"`
 }
 
 // LeaveRoomRequest 离开房间请求
@@ -74,6 +378,58 @@ type LeaveRoomRequest struct {
 	RoomID string `json:"roomId" binding:"required"`
 }
 
+// ResignRequest 认输请求
+type ResignRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	RoomID string `json:"roomId" binding:"required"`
+}
+
+// DrawOfferRequest 提议和棋请求
+type DrawOfferRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	RoomID string `json:"roomId" binding:"required"`
+}
+
+// DrawRespondRequest 回应和棋提议请求
+type DrawRespondRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	RoomID string `json:"roomId" binding:"required"`
+	Accept *bool  `json:"accept" binding:"required"` // true 同意，false 拒绝
+}
+
+// TakebackRequest 请求悔棋
+type TakebackRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	RoomID string `json:"roomId" binding:"required"`
+}
+
+// TakebackRespondRequest 回应悔棋请求
+type TakebackRespondRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	RoomID string `json:"roomId" binding:"required"`
+	Accept *bool  `json:"accept" binding:"required"` // true 同意，false 拒绝
+}
+
+// RematchRequest 提议再来一局请求
+type RematchRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	RoomID string `json:"roomId" binding:"required"`
+}
+
+// RematchRespondRequest 回应再来一局提议请求
+type RematchRespondRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	RoomID string `json:"roomId" binding:"required"`
+	Accept *bool  `json:"accept" binding:"required"` // true 同意，false 拒绝
+}
+
+// OpeningChooseRequest 开局阶段选择颜色请求
+type OpeningChooseRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	RoomID string `json:"roomId" binding:"required"`
+	Choice string `json:"choice" binding:"required"` // black, white, place2（仅 swap2 第一次选择）
+}
+
 // TokenRequest 获取令牌请求
 type TokenRequest struct {
 	UserID string `json:"userId" binding:"required"`
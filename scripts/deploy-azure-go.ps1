@@ -242,7 +242,7 @@ if (-not $ConfigureEnv) {
       COSMOS_ENDPOINT="https://your-account.documents.azure.com:443/"
       COSMOS_KEY="your-cosmos-key"
       COSMOS_DATABASE="gomoku"
-      COSMOS_CONTAINER="game_rooms"
+      COSMOS_CONTAINER="rooms"
       PUBSUB_CONNECTION_STRING="your-pubsub-connection-string"
       PUBSUB_HUB_NAME="gomoku"
       PORT="8080"